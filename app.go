@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-github/v50/github"
+)
+
+const (
+	// appJWTLifetime is how long the JWTs authenticating as a GitHub App are
+	// valid for. GitHub accepts at most 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+	// appJWTClockSkew backdates the JWTs authenticating as a GitHub App in
+	// case our clock is ahead of GitHub's.
+	appJWTClockSkew = time.Minute
+	// tokenRefreshMargin is how long before an installation token expires
+	// that it's replaced, so that requests in flight don't fail.
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// githubApp authenticates as a GitHub App, so that labels and comments are
+// attributed to the app and its installations' rate limits apply rather than
+// those of GITHUB_TOKEN.
+type githubApp struct {
+	// client is authenticated as the app itself, which can only be used to
+	// find its installations and create installation tokens.
+	client *github.Client
+	// newClient creates a client using the given HTTP client, so that the
+	// app's clients can be pointed at the right API.
+	newClient func(*http.Client) *github.Client
+	now       func() time.Time
+
+	mu            sync.Mutex
+	installations map[int64]*github.Client
+}
+
+// newGitHubApp returns a githubApp for the app with the given ID and PEM
+// encoded private key, whose clients are created by newClient.
+func newGitHubApp(appID int64, privateKeyPEM []byte, newClient func(*http.Client) *github.Client) (*githubApp, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+
+	app := &githubApp{
+		newClient:     newClient,
+		now:           time.Now,
+		installations: make(map[int64]*github.Client),
+	}
+	app.client = newClient(&http.Client{Transport: &appTransport{appID: appID, key: key, base: http.DefaultTransport, now: app.clock}})
+	return app, nil
+}
+
+// installationClient returns a client authenticated as the given installation
+// of the app. Clients are reused, so that their tokens are too, and replace
+// their tokens shortly before they expire.
+func (a *githubApp) installationClient(installationID int64) *github.Client {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if client, ok := a.installations[installationID]; ok {
+		return client
+	}
+
+	client := a.newClient(&http.Client{Transport: &installationTransport{
+		installationID: installationID,
+		apps:           a.client.Apps,
+		base:           http.DefaultTransport,
+		now:            a.clock,
+	}})
+	a.installations[installationID] = client
+	return client
+}
+
+// clock returns the current time, which tests can control by replacing now.
+func (a *githubApp) clock() time.Time {
+	return a.now()
+}
+
+// repositoryInstallation returns the ID of the app's installation on the
+// given repository.
+func (a *githubApp) repositoryInstallation(ctx context.Context, owner, repo string) (int64, error) {
+	installation, _, err := a.client.Apps.FindRepositoryInstallation(ctx, owner, repo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the GitHub App's installation on %s/%s: %w", owner, repo, err)
+	}
+	return installation.GetID(), nil
+}
+
+// appTransport authenticates requests as a GitHub App with a short-lived JWT
+// signed by its private key.
+type appTransport struct {
+	appID int64
+	key   *rsa.PrivateKey
+	base  http.RoundTripper
+	now   func() time.Time
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	now := t.now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Issuer:    strconv.FormatInt(t.appID, 10),
+		IssuedAt:  jwt.NewNumericDate(now.Add(-appJWTClockSkew)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(appJWTLifetime)),
+	}).SignedString(t.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// installationTransport authenticates requests with an installation token of
+// a GitHub App, creating a new one shortly before the current one expires.
+type installationTransport struct {
+	installationID int64
+	apps           *github.AppsService
+	base           http.RoundTripper
+	now            func() time.Time
+
+	mu    sync.Mutex
+	token *github.InstallationToken
+}
+
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.currentToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+token)
+	return t.base.RoundTrip(req)
+}
+
+// currentToken returns the installation token, creating a new one if there
+// isn't one yet or it's about to expire.
+func (t *installationTransport) currentToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != nil && t.now().Add(tokenRefreshMargin).Before(t.token.GetExpiresAt().Time) {
+		return t.token.GetToken(), nil
+	}
+
+	token, _, err := t.apps.CreateInstallationToken(ctx, t.installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a token for GitHub App installation %d: %w", t.installationID, err)
+	}
+	t.token = token
+	return token.GetToken(), nil
+}
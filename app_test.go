@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-github/v50/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// testGitHubAppAPI fakes the endpoints of the GitHub API a GitHub App uses to
+// authenticate, and records how label requests were authenticated.
+type testGitHubAppAPI struct {
+	key *rsa.PrivateKey
+	now func() time.Time
+
+	mu             sync.Mutex
+	tokensCreated  int
+	authorizations []string
+}
+
+func (api *testGitHubAppAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/app/") || strings.HasSuffix(r.URL.Path, "/installation") {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		claims := &jwt.RegisteredClaims{}
+		_, err := jwt.ParseWithClaims(bearer, claims, func(*jwt.Token) (any, error) { return &api.key.PublicKey, nil },
+			jwt.WithValidMethods([]string{"RS256"}), jwt.WithTimeFunc(api.now), jwt.WithIssuer("1"))
+		if !ok || err != nil {
+			http.Error(w, `{"message": "invalid JWT"}`, http.StatusUnauthorized)
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/installation":
+		json.NewEncoder(w).Encode(github.Installation{ID: ptr(int64(42))})
+	case r.Method == http.MethodPost && r.URL.Path == "/app/installations/42/access_tokens":
+		api.tokensCreated++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(github.InstallationToken{
+			Token:     ptr("token-" + strconv.Itoa(api.tokensCreated)),
+			ExpiresAt: &github.Timestamp{Time: api.now().Add(time.Hour)},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/labels":
+		api.authorizations = append(api.authorizations, r.Header.Get("Authorization"))
+		w.Write([]byte(`[]`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestGitHubApp(t *testing.T) (*githubApp, *testGitHubAppAPI, *time.Time) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	now := time.Now()
+	api := &testGitHubAppAPI{key: key, now: func() time.Time { return now }}
+	ts := httptest.NewServer(api)
+	t.Cleanup(ts.Close)
+
+	app, err := newGitHubApp(1, privateKeyPEM, func(httpClient *http.Client) *github.Client {
+		client := github.NewClient(httpClient)
+		client.BaseURL, _ = url.Parse(ts.URL + "/")
+		return client
+	})
+	assert.NoError(t, err)
+	app.now = func() time.Time { return now }
+	return app, api, &now
+}
+
+func TestGitHubAppInstallationClient(t *testing.T) {
+	t.Parallel()
+
+	app, api, now := newTestGitHubApp(t)
+
+	installationID, err := app.repositoryInstallation(t.Context(), "owner", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), installationID)
+
+	client := app.installationClient(installationID)
+	assert.Same(t, client, app.installationClient(installationID))
+
+	listLabels := func() {
+		_, _, err := client.Issues.ListLabels(t.Context(), "owner", "repo", nil)
+		assert.NoError(t, err)
+	}
+
+	// The token is reused until it's about to expire
+	listLabels()
+	*now = now.Add(30 * time.Minute)
+	listLabels()
+	*now = now.Add(26 * time.Minute)
+	listLabels()
+
+	assert.Equal(t, 2, api.tokensCreated)
+	assert.Equal(t, []string{"token token-1", "token token-1", "token token-2"}, api.authorizations)
+}
+
+func TestNewGitHubAppInvalidKey(t *testing.T) {
+	t.Parallel()
+
+	_, err := newGitHubApp(1, []byte("not a key"), github.NewClient)
+	assert.ErrorContains(t, err, "failed to parse GitHub App private key")
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+)
+
+const (
+	// maxRateLimitWait is the longest a backfill waits for a rate limit to
+	// reset before giving up on the remaining PRs.
+	maxRateLimitWait = 15 * time.Minute
+	// defaultSecondaryRateLimitWait is how long to wait after hitting a
+	// secondary rate limit that doesn't say when to retry.
+	defaultSecondaryRateLimitWait = time.Minute
+	// maxRateLimitRetries is how many times a PR is retried after hitting a
+	// rate limit.
+	maxRateLimitRetries = 3
+)
+
+// BackfillOptions configures which PRs a backfill relabels and how.
+type BackfillOptions struct {
+	// ClosedWithinDays also relabels PRs closed in the last this many days if
+	// it's positive. Open PRs are always relabeled.
+	ClosedWithinDays int
+	// Concurrency is how many PRs are relabeled at once.
+	Concurrency int
+}
+
+// backfillResult is the outcome of relabeling a single PR in a backfill.
+type backfillResult struct {
+	Number        int
+	Title         string
+	LinesCounted  int
+	PreviousLabel string
+	Label         string
+	// Err is why the PR couldn't be relabeled, if it couldn't.
+	Err error
+	// Skipped is whether the PR wasn't relabeled because the backfill was
+	// stopped by a rate limit.
+	Skipped bool
+}
+
+// Backfill relabels every open PR in the repository, and those closed within
+// opts.ClosedWithinDays, so that they reflect the current configuration. PRs
+// are relabeled opts.Concurrency at a time. PRs that hit a rate limit are
+// retried once it resets, unless that's more than maxRateLimitWait away, in
+// which case the remaining PRs are skipped until the next backfill. Unlike
+// AddSizeLabel, only labels are updated: comments, check runs, commit
+// statuses and limits are left to PR events. A job summary lists the result
+// for each PR.
+func (l *GitHubPRSizeLabeler) Backfill(ctx context.Context, opts BackfillOptions) error {
+	l.action.Group("Backfilling size labels")
+	defer l.action.EndGroup()
+
+	prs, err := l.listPRs(ctx, "open", time.Time{})
+	if err != nil {
+		return err
+	}
+	if opts.ClosedWithinDays > 0 {
+		closed, err := l.listPRs(ctx, "closed", time.Now().AddDate(0, 0, -opts.ClosedWithinDays))
+		if err != nil {
+			return err
+		}
+		prs = append(prs, closed...)
+	}
+	l.action.Infof("Backfilling %d PRs", len(prs))
+
+	results := make([]backfillResult, len(prs))
+	var stopped atomic.Bool
+	sem := make(chan struct{}, max(opts.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, pr := range prs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if stopped.Load() || ctx.Err() != nil {
+				results[i] = backfillResult{Number: pr.GetNumber(), Title: pr.GetTitle(), Skipped: true}
+				return
+			}
+			results[i] = l.backfillPR(ctx, pr, &stopped)
+		}()
+	}
+	wg.Wait()
+
+	l.addStepSummary(renderBackfillSummary(results))
+	if l.plan != nil {
+		l.addStepSummary(l.plan.render())
+	}
+
+	var errs []error
+	skipped := 0
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("PR #%d: %w", result.Number, result.Err))
+		}
+		if result.Skipped {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		l.action.Warningf("Skipped %d PRs because of rate limiting, they will be relabeled by the next backfill", skipped)
+	}
+	return errors.Join(errs...)
+}
+
+// backfillPR relabels a single PR, retrying if it hits a rate limit. If the
+// rate limit won't reset soon enough, stopped is set so that the remaining
+// PRs are skipped.
+func (l *GitHubPRSizeLabeler) backfillPR(ctx context.Context, pr *github.PullRequest, stopped *atomic.Bool) backfillResult {
+	result := backfillResult{Number: pr.GetNumber(), Title: pr.GetTitle()}
+	prLabeler := l.ForEvent(FetchedPullRequest{pr: pr})
+
+	for attempt := 0; ; attempt++ {
+		size, err := prLabeler.calculateSize(ctx)
+		if err == nil {
+			result.LinesCounted = size.LinesCounted
+			result.Label, result.PreviousLabel, err = prLabeler.selectLabel(ctx, size)
+			if err == nil && !l.config.CheckRun.SkipLabels {
+				err = prLabeler.updateLabels(ctx, result.Label)
+			}
+		}
+		if err == nil {
+			return result
+		}
+
+		wait, ok := rateLimitWait(err)
+		if !ok || attempt >= maxRateLimitRetries {
+			result.Err = err
+			return result
+		}
+		if wait > maxRateLimitWait {
+			l.action.Warningf("Rate limited until %s, stopping the backfill", time.Now().Add(wait).Format(time.RFC3339))
+			stopped.Store(true)
+			result.Skipped = true
+			return result
+		}
+
+		l.action.Infof("Rate limited while relabeling PR #%d, retrying in %s", result.Number, wait.Round(time.Second))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+			return result
+		}
+	}
+}
+
+// rateLimitWait returns how long to wait before retrying a request that
+// failed with err, if it failed because of a rate limit.
+func rateLimitWait(err error) (time.Duration, bool) {
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return max(time.Until(rateLimitErr.Rate.Reset.Time), 0), true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if abuseErr.RetryAfter != nil {
+			return *abuseErr.RetryAfter, true
+		}
+		return defaultSecondaryRateLimitWait, true
+	}
+	return 0, false
+}
+
+// listPRs lists the repository's PRs in the given state, most recently
+// updated first. If since is set, only PRs closed since then are listed.
+func (l *GitHubPRSizeLabeler) listPRs(ctx context.Context, state string, since time.Time) ([]*github.PullRequest, error) {
+	prs := []*github.PullRequest{}
+	opts := &github.PullRequestListOptions{
+		State:       state,
+		Sort:        "updated",
+		Direction:   "desc",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	for {
+		page, resp, err := l.pullRequests.List(ctx, l.owner, l.repo, opts)
+		if err != nil {
+			return prs, err
+		}
+
+		for _, pr := range page {
+			// PRs are sorted by when they were last updated, which is never
+			// before they were closed, so none of the rest were closed since
+			if !since.IsZero() && pr.GetUpdatedAt().Before(since) {
+				l.action.Infof("Found %d %s PRs", len(prs), state)
+				return prs, nil
+			}
+			if !since.IsZero() && pr.GetClosedAt().Before(since) {
+				continue
+			}
+			prs = append(prs, pr)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	l.action.Infof("Found %d %s PRs", len(prs), state)
+	return prs, nil
+}
+
+// renderBackfillSummary renders a Markdown table of the result of relabeling
+// each PR in a backfill.
+func renderBackfillSummary(results []backfillResult) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## PR Size Backfill\n\n")
+	if len(results) == 0 {
+		fmt.Fprintf(&b, "There were no PRs to relabel.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "| PR | Lines | Previous label | Label | Result |\n")
+	fmt.Fprintf(&b, "| --- | ---: | --- | --- | --- |\n")
+	for _, r := range results {
+		var outcome string
+		switch {
+		case r.Skipped:
+			outcome = "skipped (rate limited)"
+		case r.Err != nil:
+			outcome = fmt.Sprintf("failed: %v", r.Err)
+		case r.Label == r.PreviousLabel:
+			outcome = "unchanged"
+		default:
+			outcome = "relabeled"
+		}
+
+		lines := ""
+		if !r.Skipped && r.Err == nil {
+			lines = fmt.Sprintf("%d", r.LinesCounted)
+		}
+		fmt.Fprintf(&b, "| #%d %s | %s | %s | %s | %s |\n",
+			r.Number, strings.ReplaceAll(r.Title, "|", `\|`), lines, codeOrEmpty(r.PreviousLabel), codeOrEmpty(r.Label), outcome)
+	}
+	return b.String()
+}
+
+// codeOrEmpty formats s as inline code, or returns an empty string if s is
+// empty.
+func codeOrEmpty(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
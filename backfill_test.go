@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPullRequest(number int, state string, labels []string, closedAgo time.Duration) *github.PullRequest {
+	event := newTestGitHubPullRequestEvent(number, "repo", "owner", labels)
+	pr := event.event.PullRequest
+	pr.State = ptr(state)
+	pr.Title = ptr("Change things")
+	pr.UpdatedAt = &github.Timestamp{Time: time.Now().Add(-closedAgo)}
+	if state == "closed" {
+		pr.ClosedAt = &github.Timestamp{Time: time.Now().Add(-closedAgo)}
+	}
+	return pr
+}
+
+func newTestBackfillLabeler(t *testing.T, issuesClient *mocks.IssuesClient, prClient *mocks.PullRequestsClient) (*GitHubPRSizeLabeler, string) {
+	summaryPath := filepath.Join(t.TempDir(), "summary")
+	action := githubactions.New(githubactions.WithGetenv(func(key string) string {
+		if key == "GITHUB_STEP_SUMMARY" {
+			return summaryPath
+		}
+		return ""
+	}))
+
+	config := Config{
+		Labels: []Label{
+			{Name: "size/S", MinLines: 0},
+			{Name: "size/L", MinLines: 100},
+		},
+	}
+	reposClient := mocks.NewRepositoriesClient()
+	labeler := newGitHubPRSizeLabeler(issuesClient, prClient, reposClient, mocks.NewGitClient(reposClient), mocks.NewUsersClient(), mocks.NewChecksClient(), action, config, "owner", "repo")
+	labeler.git = nil
+	return labeler, summaryPath
+}
+
+func TestBackfill(t *testing.T) {
+	t.Parallel()
+
+	mockIssues := mocks.NewIssuesClient()
+	mockPR := mocks.NewPullRequestsClient()
+	mockPR.PullRequests = []*github.PullRequest{
+		newTestPullRequest(1, "open", []string{"size/S"}, 0),
+		newTestPullRequest(2, "open", []string{"size/L"}, 0),
+		newTestPullRequest(3, "open", nil, 0),
+		newTestPullRequest(4, "closed", []string{"size/S", "bug"}, 2*24*time.Hour),
+		newTestPullRequest(5, "closed", []string{"size/S"}, 30*24*time.Hour),
+	}
+	large := []*github.CommitFile{{Filename: ptr("main.go"), Additions: ptr(150), Deletions: ptr(0), Patch: ptr("")}}
+	small := []*github.CommitFile{{Filename: ptr("main.go"), Additions: ptr(5), Deletions: ptr(0), Patch: ptr("")}}
+	mockPR.FilesChangedByPR = map[int][]*github.CommitFile{1: large, 2: large, 3: small, 4: large, 5: large}
+
+	labeler, summaryPath := newTestBackfillLabeler(t, mockIssues, mockPR)
+
+	err := labeler.Backfill(t.Context(), BackfillOptions{ClosedWithinDays: 7, Concurrency: 2})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[int][]string{1: {"size/L"}, 3: {"size/S"}, 4: {"size/L"}}, mockIssues.AddedLabelsByIssue)
+	assert.Equal(t, map[int][]string{1: {"size/S"}, 4: {"size/S"}}, mockIssues.RemovedLabelsByIssue)
+
+	summary, err := os.ReadFile(summaryPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(summary), "| #1 Change things | 150 | `size/S` | `size/L` | relabeled |\n")
+	assert.Contains(t, string(summary), "| #2 Change things | 150 | `size/L` | `size/L` | unchanged |\n")
+	assert.Contains(t, string(summary), "| #3 Change things | 5 |  | `size/S` | relabeled |\n")
+	assert.Contains(t, string(summary), "| #4 Change things | 150 | `size/S` | `size/L` | relabeled |\n")
+	assert.NotContains(t, string(summary), "#5")
+}
+
+func TestBackfillRateLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		err             error
+		expectedAdded   map[int][]string
+		expectedSkipped bool
+	}{
+		{
+			name:          "retries once the rate limit resets",
+			err:           &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(-time.Second)}}},
+			expectedAdded: map[int][]string{1: {"size/L"}, 2: {"size/L"}},
+		},
+		{
+			name:          "retries after a secondary rate limit",
+			err:           &github.AbuseRateLimitError{RetryAfter: ptr(time.Duration(0))},
+			expectedAdded: map[int][]string{1: {"size/L"}, 2: {"size/L"}},
+		},
+		{
+			name:            "stops if the rate limit resets too late",
+			err:             &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}}},
+			expectedAdded:   map[int][]string{},
+			expectedSkipped: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.PullRequests = []*github.PullRequest{
+				newTestPullRequest(1, "open", nil, 0),
+				newTestPullRequest(2, "open", nil, 0),
+			}
+			mockPR.FilesChanged = []*github.CommitFile{{Filename: ptr("main.go"), Additions: ptr(150), Deletions: ptr(0), Patch: ptr("")}}
+			mockPR.ListFilesErrs = []error{tc.err}
+
+			labeler, summaryPath := newTestBackfillLabeler(t, mockIssues, mockPR)
+
+			err := labeler.Backfill(t.Context(), BackfillOptions{Concurrency: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedAdded, mockIssues.AddedLabelsByIssue)
+
+			summary, err := os.ReadFile(summaryPath)
+			assert.NoError(t, err)
+			if tc.expectedSkipped {
+				assert.Contains(t, string(summary), "| #1 Change things |  |  |  | skipped (rate limited) |\n")
+				assert.Contains(t, string(summary), "| #2 Change things |  |  |  | skipped (rate limited) |\n")
+			}
+		})
+	}
+}
+
+func TestBackfillErrors(t *testing.T) {
+	t.Parallel()
+
+	mockIssues := mocks.NewIssuesClient()
+	mockIssues.AddLabelsErr = assert.AnError
+	mockPR := mocks.NewPullRequestsClient()
+	mockPR.PullRequests = []*github.PullRequest{newTestPullRequest(7, "open", nil, 0)}
+
+	labeler, _ := newTestBackfillLabeler(t, mockIssues, mockPR)
+
+	err := labeler.Backfill(t.Context(), BackfillOptions{Concurrency: 4})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.ErrorContains(t, err, "PR #7: ")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// FileChange is a single file changed between the base and head of a change.
+type FileChange struct {
+	Filename string
+	// Status is one of "added", "removed", "modified" or "renamed".
+	Status    string
+	Additions int
+	Deletions int
+	// PreviousFilename is the file's name at the base if it was renamed.
+	PreviousFilename string
+	// Patch is the unified diff of the file's hunks, if the source has it.
+	Patch string
+	// Binary is whether the file is binary, in which case it has no line
+	// counts or patch.
+	Binary bool
+}
+
+// ChangeSource provides the files changed by a PR or other change, so that the
+// same sizing rules can be applied regardless of where the changes come from.
+type ChangeSource interface {
+	FilesChanged(ctx context.Context) ([]FileChange, error)
+}
+
+// maxListedFiles is the most files GitHub lists for a PR.
+const maxListedFiles = 3000
+
+// githubChangeSource lists the files changed in a PR through the GitHub API.
+// GitHub lists at most maxListedFiles files, so for larger PRs the files are
+// listed with a local git diff between the base and head commits if they have
+// been fetched. Otherwise only the listed files are sized, so the size is a
+// lower bound.
+type githubChangeSource struct {
+	action       *githubactions.Action
+	pullRequests PullRequestsClient
+	event        LabelEvent
+	// git is the local checkout to fall back to, or nil to only use the API.
+	git *gitRepo
+}
+
+func (s *githubChangeSource) FilesChanged(ctx context.Context) ([]FileChange, error) {
+	filesChanged, err := s.listFiles(ctx)
+	if err != nil || !s.truncated(len(filesChanged)) {
+		return filesChanged, err
+	}
+
+	if s.event.PRChangedFiles() > 0 {
+		s.action.Warningf("PR #%d changes %d files but the API only listed %d of them",
+			s.event.PRNumber(), s.event.PRChangedFiles(), len(filesChanged))
+	} else {
+		s.action.Warningf("The API listed %d files for PR #%d, as many as it lists, so some may be missing",
+			len(filesChanged), s.event.PRNumber())
+	}
+
+	if s.git != nil {
+		files, err := s.gitFilesChanged(ctx)
+		if err == nil {
+			s.action.Warningf("Sized PR #%d from a local git diff, which may differ slightly from the API's", s.event.PRNumber())
+			return files, nil
+		}
+		s.action.Infof("Failed to diff the PR locally, make sure the base and head commits are fetched: %v", err)
+	}
+
+	s.action.Warningf("Sized PR #%d from the %d files the API listed, so its size is a lower bound", s.event.PRNumber(), len(filesChanged))
+	return filesChanged, nil
+}
+
+// truncated returns whether GitHub listed only some of the PR's files, given
+// the number it listed. PRs listed rather than fetched, such as those relabeled
+// by a backfill, don't have their number of changed files, so they're assumed
+// to be truncated if GitHub listed as many files as it can.
+func (s *githubChangeSource) truncated(listed int) bool {
+	if changed := s.event.PRChangedFiles(); changed > 0 {
+		return listed < changed
+	}
+	return listed >= maxListedFiles
+}
+
+// listFiles lists the files changed in the PR with the PR files API.
+func (s *githubChangeSource) listFiles(ctx context.Context) ([]FileChange, error) {
+	filesChanged := []FileChange{}
+
+	s.action.Infof("Getting files changed in pr #%d", s.event.PRNumber())
+
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		page, resp, err := s.pullRequests.ListFiles(ctx, s.event.RepoOwner(), s.event.RepoName(), s.event.PRNumber(), opts)
+		if err != nil {
+			return filesChanged, err
+		}
+
+		for _, c := range page {
+			filesChanged = append(filesChanged, githubFileChange(c))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	s.action.Infof("Found %d files changed in pr", len(filesChanged))
+	return filesChanged, nil
+}
+
+// gitFilesChanged lists the files changed in the PR with a local git diff
+// between the merge base of its base and head commits and its head commit.
+func (s *githubChangeSource) gitFilesChanged(ctx context.Context) ([]FileChange, error) {
+	mergeBase, err := s.git.mergeBase(ctx, s.event.BaseSHA(), s.event.HeadSHA())
+	if err != nil {
+		return nil, err
+	}
+	return s.git.filesChanged(ctx, mergeBase, s.event.HeadSHA())
+}
+
+// githubFileChange converts a file from the GitHub API. GitHub doesn't include
+// a patch or line counts for binary files, but neither does it for files that
+// are renamed, only change mode ("changed") or are added or removed empty, so
+// only modified files are known to be binary.
+func githubFileChange(file *github.CommitFile) FileChange {
+	return FileChange{
+		Filename:         file.GetFilename(),
+		Status:           file.GetStatus(),
+		Additions:        file.GetAdditions(),
+		Deletions:        file.GetDeletions(),
+		PreviousFilename: file.GetPreviousFilename(),
+		Patch:            file.GetPatch(),
+		Binary: file.Patch == nil && file.GetAdditions() == 0 && file.GetDeletions() == 0 &&
+			file.GetStatus() == "modified",
+	}
+}
+
+// gitChangeSource lists the files changed between two revisions of a local git
+// repository.
+type gitChangeSource struct {
+	repo gitRepo
+	base string
+	head string
+}
+
+func (s *gitChangeSource) FilesChanged(ctx context.Context) ([]FileChange, error) {
+	return s.repo.filesChanged(ctx, s.base, s.head)
+}
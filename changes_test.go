@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGithubFileChange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		file     *github.CommitFile
+		expected FileChange
+	}{
+		{
+			name: "text file",
+			file: &github.CommitFile{
+				Filename:  ptr("main.go"),
+				Status:    ptr("modified"),
+				Additions: ptr(3),
+				Deletions: ptr(1),
+				Patch:     ptr("@@ -1 +1,3 @@"),
+			},
+			expected: FileChange{Filename: "main.go", Status: "modified", Additions: 3, Deletions: 1, Patch: "@@ -1 +1,3 @@"},
+		},
+		{
+			name: "binary file",
+			file: &github.CommitFile{
+				Filename:  ptr("logo.png"),
+				Status:    ptr("modified"),
+				Additions: ptr(0),
+				Deletions: ptr(0),
+			},
+			expected: FileChange{Filename: "logo.png", Status: "modified", Binary: true},
+		},
+		{
+			name: "renamed without changes",
+			file: &github.CommitFile{
+				Filename:         ptr("new.go"),
+				PreviousFilename: ptr("old.go"),
+				Status:           ptr("renamed"),
+				Additions:        ptr(0),
+				Deletions:        ptr(0),
+			},
+			expected: FileChange{Filename: "new.go", PreviousFilename: "old.go", Status: "renamed"},
+		},
+		{
+			name: "empty file added",
+			file: &github.CommitFile{
+				Filename:  ptr(".keep"),
+				Status:    ptr("added"),
+				Additions: ptr(0),
+				Deletions: ptr(0),
+				Changes:   ptr(0),
+			},
+			expected: FileChange{Filename: ".keep", Status: "added"},
+		},
+		{
+			name: "mode change",
+			file: &github.CommitFile{
+				Filename:  ptr("script.sh"),
+				Status:    ptr("changed"),
+				Additions: ptr(0),
+				Deletions: ptr(0),
+				Changes:   ptr(0),
+			},
+			expected: FileChange{Filename: "script.sh", Status: "changed"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, githubFileChange(tc.file))
+		})
+	}
+}
+
+func newTestTruncatedEvent(changedFiles int) PullRequestEvent {
+	event := newTestGitHubPullRequestEvent(1, "test-repo", "test-owner", nil)
+	event.event.PullRequest.ChangedFiles = ptr(changedFiles)
+	return event
+}
+
+func testCommitFiles(n int, prefix string) []*github.CommitFile {
+	files := make([]*github.CommitFile, n)
+	for i := range files {
+		files[i] = &github.CommitFile{
+			Filename:  ptr(fmt.Sprintf("%s%d.go", prefix, i)),
+			Status:    ptr("modified"),
+			Additions: ptr(1),
+			Deletions: ptr(0),
+			Patch:     ptr("@@ -0,0 +1 @@"),
+		}
+	}
+	return files
+}
+
+func TestGithubChangeSourceTruncated(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		changedFiles      int
+		listedFiles       []*github.CommitFile
+		expectedFileCount int
+	}{
+		{
+			name:              "not truncated",
+			changedFiles:      3,
+			listedFiles:       testCommitFiles(3, "listed"),
+			expectedFileCount: 3,
+		},
+		{
+			name:              "changed files unknown",
+			changedFiles:      0,
+			listedFiles:       testCommitFiles(3, "listed"),
+			expectedFileCount: 3,
+		},
+		{
+			name:              "changed files unknown and limit reached",
+			changedFiles:      0,
+			listedFiles:       testCommitFiles(3000, "listed"),
+			expectedFileCount: 3000,
+		},
+		{
+			name:              "truncated without a checkout sizes the listed files",
+			changedFiles:      3500,
+			listedFiles:       testCommitFiles(3000, "listed"),
+			expectedFileCount: 3000,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			prClient := mocks.NewPullRequestsClient()
+			prClient.FilesChanged = tc.listedFiles
+
+			source := &githubChangeSource{
+				action:       githubactions.New(githubactions.WithWriter(io.Discard)),
+				pullRequests: prClient,
+				event:        newTestTruncatedEvent(tc.changedFiles),
+			}
+			files, err := source.FilesChanged(context.Background())
+
+			assert.NoError(t, err)
+			assert.Len(t, files, tc.expectedFileCount)
+		})
+	}
+}
+
+func TestGithubChangeSourceTruncatedGit(t *testing.T) {
+	newTestGitRepo(t,
+		map[string]string{"main.go": "package main\n"},
+		map[string]string{"a.go": "a\n", "b.go": "b\nb\n", "main.go": "package main\n\nfunc main() {}\n"},
+	)
+	repo := &gitRepo{}
+	baseSHA, err := repo.run(context.Background(), "rev-parse", "main")
+	assert.NoError(t, err)
+	headSHA, err := repo.run(context.Background(), "rev-parse", "feature")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		changedFiles *int
+		listedFiles  []*github.CommitFile
+	}{
+		{
+			name:         "fewer files listed than changed",
+			changedFiles: ptr(3),
+			listedFiles:  testCommitFiles(1, "listed"),
+		},
+		{
+			name:        "changed files unknown and limit reached",
+			listedFiles: testCommitFiles(maxListedFiles, "listed"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// PRs listed by a backfill don't have their number of changed files
+			pr := &github.PullRequest{
+				Number:       ptr(1),
+				ChangedFiles: tc.changedFiles,
+				Base:         &github.PullRequestBranch{SHA: ptr(strings.TrimSpace(string(baseSHA))), Repo: &github.Repository{Name: ptr("test-repo"), Owner: &github.User{Login: ptr("test-owner")}}},
+				Head:         &github.PullRequestBranch{SHA: ptr(strings.TrimSpace(string(headSHA)))},
+			}
+			prClient := mocks.NewPullRequestsClient()
+			prClient.FilesChanged = tc.listedFiles
+
+			source := &githubChangeSource{
+				action:       githubactions.New(githubactions.WithWriter(io.Discard)),
+				pullRequests: prClient,
+				event:        FetchedPullRequest{pr: pr},
+				git:          repo,
+			}
+			files, err := source.FilesChanged(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, []FileChange{
+				{Filename: "a.go", Status: "modified", Additions: 1},
+				{Filename: "b.go", Status: "modified", Additions: 2},
+				{Filename: "main.go", Status: "modified", Additions: 2},
+			}, files)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v50/github"
+)
+
+const (
+	defaultCheckRunName = "PR Size"
+	// maxCheckRunSummary is the longest summary the Checks API accepts.
+	maxCheckRunSummary = 65535
+	// maxCheckRunAnnotations is the most annotations the Checks API accepts
+	// in a single request.
+	maxCheckRunAnnotations = 50
+)
+
+// CreateCheckRun publishes a completed check run on the PR's head commit
+// reporting its size. The conclusion comes from the label's check-conclusion,
+// and is failure if the PR breaks the size limit (or neutral if the limit
+// only warns). Files are annotated with why they were ignored, and if the PR
+// breaks the limit, the largest files are annotated with how much they count.
+func (l *GitHubPRSizeLabeler) CreateCheckRun(ctx context.Context, size prSize, label, limitViolation string) error {
+	name := l.config.CheckRun.Name
+	if name == "" {
+		name = defaultCheckRunName
+	}
+
+	conclusion := l.checkConclusion(label, limitViolation)
+	title := fmt.Sprintf("%d lines", size.LinesCounted)
+	if label != "" {
+		title = fmt.Sprintf("%d lines (%s)", size.LinesCounted, label)
+	}
+	summary := truncate(renderSummary(l.event.PRNumber(), size, l.config.Labels, label, limitViolation), maxCheckRunSummary)
+
+	l.action.Infof("Creating check run %q with conclusion %s", name, conclusion)
+	_, _, err := l.checks.CreateCheckRun(ctx, l.event.RepoOwner(), l.event.RepoName(), github.CreateCheckRunOptions{
+		Name:        name,
+		HeadSHA:     l.event.HeadSHA(),
+		Status:      github.String("completed"),
+		Conclusion:  &conclusion,
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+		Output: &github.CheckRunOutput{
+			Title:       &title,
+			Summary:     &summary,
+			Annotations: l.checkRunAnnotations(size, limitViolation),
+		},
+	})
+	return err
+}
+
+// checkRunAnnotations returns the annotations of the check run, at most
+// maxCheckRunAnnotations of them. If the PR breaks the size limit, its largest
+// files are annotated first, at the level of the limit's mode, followed by
+// notices on the files that were ignored. Files without additions are
+// skipped, since they may not exist at the head commit.
+func (l *GitHubPRSizeLabeler) checkRunAnnotations(size prSize, limitViolation string) []*github.CheckRunAnnotation {
+	annotations := []*github.CheckRunAnnotation{}
+	annotate := func(f fileSize, level, message string) {
+		if len(annotations) < maxCheckRunAnnotations && f.Additions > 0 {
+			annotations = append(annotations, &github.CheckRunAnnotation{
+				Path:            github.String(f.Filename),
+				StartLine:       github.Int(1),
+				EndLine:         github.Int(1),
+				AnnotationLevel: github.String(level),
+				Message:         github.String(message),
+			})
+		}
+	}
+
+	files := sortedFiles(size)
+	if limitViolation != "" {
+		level := "failure"
+		if l.config.Limit.Mode == LimitModeWarn {
+			level = "warning"
+		}
+		for _, f := range files {
+			if f.IgnoredReason == "" && f.LinesCounted > 0 {
+				annotate(f, level, fmt.Sprintf("This file counts %g lines toward the PR's %d, which exceed the limit of %d set by limit.max-lines",
+					f.LinesCounted, size.LinesCounted, l.config.Limit.MaxLines))
+			}
+		}
+	}
+	for _, f := range files {
+		if f.IgnoredReason != "" {
+			annotate(f, "notice", fmt.Sprintf("This file isn't counted toward the PR's size: %s", f.IgnoredReason))
+		}
+	}
+	return annotations
+}
+
+// checkConclusion returns the conclusion of the check run for a PR with the
+// given label and limit violation.
+func (l *GitHubPRSizeLabeler) checkConclusion(label, limitViolation string) string {
+	conclusion := "success"
+	if sizeLabel, ok := l.config.label(label); ok && sizeLabel.CheckConclusion != "" {
+		conclusion = sizeLabel.CheckConclusion
+	}
+
+	if limitViolation == "" {
+		return conclusion
+	}
+	if l.config.Limit.Mode != LimitModeWarn {
+		return "failure"
+	}
+	if conclusion == "success" {
+		return "neutral"
+	}
+	return conclusion
+}
+
+// truncate shortens s to at most n bytes, noting that it was truncated.
+func truncate(s string, n int) string {
+	const suffix = "\n\n_Truncated_\n"
+	if len(s) <= n {
+		return s
+	}
+
+	end := n - len(suffix)
+	// Don't cut a multi-byte character in half
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + suffix
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddSizeLabelCreatesCheckRun(t *testing.T) {
+	t.Parallel()
+
+	labels := []Label{
+		{Name: "size/S", MinLines: 0},
+		{Name: "size/L", MinLines: 100, CheckConclusion: "neutral"},
+		{Name: "size/XXL", MinLines: 1000, CheckConclusion: "failure"},
+	}
+
+	tests := []struct {
+		name               string
+		lines              int
+		checkRun           CheckRunConfig
+		limit              LimitConfig
+		expectedName       string
+		expectedTitle      string
+		expectedConclusion string
+		expectedLabels     []string
+	}{
+		{
+			name:               "success by default",
+			lines:              10,
+			checkRun:           CheckRunConfig{Enabled: true},
+			expectedName:       "PR Size",
+			expectedTitle:      "10 lines (size/S)",
+			expectedConclusion: "success",
+			expectedLabels:     []string{"size/S"},
+		},
+		{
+			name:               "conclusion from label policy",
+			lines:              412,
+			checkRun:           CheckRunConfig{Enabled: true, Name: "Size"},
+			expectedName:       "Size",
+			expectedTitle:      "412 lines (size/L)",
+			expectedConclusion: "neutral",
+			expectedLabels:     []string{"size/L"},
+		},
+		{
+			name:               "failure when exceeding the limit",
+			lines:              500,
+			checkRun:           CheckRunConfig{Enabled: true},
+			limit:              LimitConfig{MaxLines: 400, Mode: LimitModeFail},
+			expectedName:       "PR Size",
+			expectedTitle:      "500 lines (size/L)",
+			expectedConclusion: "failure",
+			expectedLabels:     []string{"size/L"},
+		},
+		{
+			name:               "neutral when exceeding a warning limit",
+			lines:              50,
+			checkRun:           CheckRunConfig{Enabled: true},
+			limit:              LimitConfig{MaxLines: 40, Mode: LimitModeWarn},
+			expectedName:       "PR Size",
+			expectedTitle:      "50 lines (size/S)",
+			expectedConclusion: "neutral",
+			expectedLabels:     []string{"size/S"},
+		},
+		{
+			name:               "labels can be skipped",
+			lines:              2000,
+			checkRun:           CheckRunConfig{Enabled: true, SkipLabels: true},
+			expectedName:       "PR Size",
+			expectedTitle:      "2000 lines (size/XXL)",
+			expectedConclusion: "failure",
+			expectedLabels:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = []*github.CommitFile{
+				{Filename: ptr("file1.go"), Additions: ptr(tt.lines), Deletions: ptr(0)},
+			}
+			mockChecks := mocks.NewChecksClient()
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				Config{Labels: labels, CheckRun: tt.checkRun, Limit: tt.limit},
+				mockIssues,
+				mockPR,
+				mocks.NewRepositoriesClient(),
+			)
+			labeler.checks = mockChecks
+
+			// The limit's error is covered elsewhere
+			_ = labeler.AddSizeLabel(t.Context())
+
+			assert.Equal(t, tt.expectedLabels, mockIssues.AddedLabels)
+			assert.Len(t, mockChecks.CreatedCheckRuns, 1)
+			checkRun := mockChecks.CreatedCheckRuns[0]
+			assert.Equal(t, tt.expectedName, checkRun.Name)
+			assert.Equal(t, testHeadSHA, checkRun.HeadSHA)
+			assert.Equal(t, "completed", *checkRun.Status)
+			assert.Equal(t, tt.expectedConclusion, *checkRun.Conclusion)
+			assert.Equal(t, tt.expectedTitle, *checkRun.Output.Title)
+			assert.Contains(t, *checkRun.Output.Summary, "## PR Size")
+		})
+	}
+}
+
+func TestCheckRunAnnotations(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		limit    LimitConfig
+		expected []*github.CheckRunAnnotation
+	}{
+		{
+			name: "ignored files within the limit",
+			expected: []*github.CheckRunAnnotation{
+				{Path: ptr("vendor/lib.go"), StartLine: ptr(1), EndLine: ptr(1), AnnotationLevel: ptr("notice"), Message: ptr("This file isn't counted toward the PR's size: excluded by `vendor/**`")},
+			},
+		},
+		{
+			name:  "largest files over the limit",
+			limit: LimitConfig{MaxLines: 400, Mode: LimitModeFail},
+			expected: []*github.CheckRunAnnotation{
+				{Path: ptr("big.go"), StartLine: ptr(1), EndLine: ptr(1), AnnotationLevel: ptr("failure"), Message: ptr("This file counts 450 lines toward the PR's 500, which exceed the limit of 400 set by limit.max-lines")},
+				{Path: ptr("small.go"), StartLine: ptr(1), EndLine: ptr(1), AnnotationLevel: ptr("failure"), Message: ptr("This file counts 50 lines toward the PR's 500, which exceed the limit of 400 set by limit.max-lines")},
+				{Path: ptr("vendor/lib.go"), StartLine: ptr(1), EndLine: ptr(1), AnnotationLevel: ptr("notice"), Message: ptr("This file isn't counted toward the PR's size: excluded by `vendor/**`")},
+			},
+		},
+		{
+			name:  "warnings over a warning limit",
+			limit: LimitConfig{MaxLines: 400, Mode: LimitModeWarn},
+			expected: []*github.CheckRunAnnotation{
+				{Path: ptr("big.go"), StartLine: ptr(1), EndLine: ptr(1), AnnotationLevel: ptr("warning"), Message: ptr("This file counts 450 lines toward the PR's 500, which exceed the limit of 400 set by limit.max-lines")},
+				{Path: ptr("small.go"), StartLine: ptr(1), EndLine: ptr(1), AnnotationLevel: ptr("warning"), Message: ptr("This file counts 50 lines toward the PR's 500, which exceed the limit of 400 set by limit.max-lines")},
+				{Path: ptr("vendor/lib.go"), StartLine: ptr(1), EndLine: ptr(1), AnnotationLevel: ptr("notice"), Message: ptr("This file isn't counted toward the PR's size: excluded by `vendor/**`")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = []*github.CommitFile{
+				{Filename: ptr("small.go"), Status: ptr("modified"), Additions: ptr(50), Deletions: ptr(0)},
+				{Filename: ptr("big.go"), Status: ptr("modified"), Additions: ptr(400), Deletions: ptr(50)},
+				{Filename: ptr("vendor/lib.go"), Status: ptr("added"), Additions: ptr(300), Deletions: ptr(0)},
+				{Filename: ptr("old.go"), Status: ptr("removed"), Additions: ptr(0), Deletions: ptr(0)},
+			}
+			mockChecks := mocks.NewChecksClient()
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				Config{
+					Labels:       []Label{{Name: "size/S", MinLines: 0}},
+					CheckRun:     CheckRunConfig{Enabled: true},
+					Limit:        tt.limit,
+					ExcludePaths: []string{"vendor/**"},
+				},
+				mocks.NewIssuesClient(),
+				mockPR,
+				mocks.NewRepositoriesClient(),
+			)
+			labeler.checks = mockChecks
+
+			// The limit's error is covered elsewhere
+			_ = labeler.AddSizeLabel(t.Context())
+
+			assert.Len(t, mockChecks.CreatedCheckRuns, 1)
+			assert.Equal(t, tt.expected, mockChecks.CreatedCheckRuns[0].Output.Annotations)
+		})
+	}
+}
+
+func TestCreateSizeLabelsSkipLabels(t *testing.T) {
+	t.Parallel()
+
+	mockIssues := mocks.NewIssuesClient()
+	labeler := newTestLabeler(
+		newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+		Config{
+			Labels:   []Label{{Name: "size/S", MinLines: 0}},
+			CheckRun: CheckRunConfig{Enabled: true, SkipLabels: true},
+		},
+		mockIssues,
+		mocks.NewPullRequestsClient(),
+		mocks.NewRepositoriesClient(),
+	)
+
+	err := labeler.CreateSizeLabels(t.Context())
+	assert.NoError(t, err)
+	assert.Empty(t, mockIssues.CreatedLabels)
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "short", truncate("short", 100))
+
+	truncated := truncate(strings.Repeat("⬅️", 100), 50)
+	assert.LessOrEqual(t, len(truncated), 50)
+	assert.True(t, strings.HasSuffix(truncated, "_Truncated_\n"))
+	assert.True(t, utf8.ValidString(truncated))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// runSize implements the size command, which sizes the changes between two
+// revisions of the local git repository, or in a unified diff, using the same
+// rules as the action, without talking to GitHub. It prints the label the
+// changes would get along with a breakdown of each file, and returns a
+// non-zero exit code if they exceed limit.max-lines in fail mode, so that it
+// can be used as a pre-push hook.
+func runSize(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("size", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	base := flags.String("base", "origin/main", "revision the changes will be merged into")
+	head := flags.String("head", "HEAD", "revision containing the changes")
+	diffPath := flags.String("diff", "", "size a unified diff file instead of git revisions, or - for stdin")
+	configPath := flags.String("config", ".github/pr-size-labeler.yml", "path to the pr-size-labeler config file")
+	verbose := flags.Bool("v", false, "log how the size is calculated")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	logs := io.Discard
+	if *verbose {
+		logs = stderr
+	}
+	action := githubactions.New(githubactions.WithWriter(logs))
+
+	var source ChangeSource
+	var loadGitattributesFile gitattributesLoader
+	switch *diffPath {
+	case "":
+		repo := gitRepo{}
+		mergeBase, err := repo.mergeBase(ctx, *base, *head)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		source = &gitChangeSource{repo: repo, base: mergeBase, head: *head}
+		loadGitattributesFile = repo.gitattributesLoader(mergeBase)
+	case "-":
+		source = &diffChangeSource{r: stdin}
+		loadGitattributesFile = readLocalGitattributes
+	default:
+		f, err := fs.Open(*diffPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		defer f.Close()
+		source = &diffChangeSource{r: f}
+		loadGitattributesFile = readLocalGitattributes
+	}
+
+	filesChanged, err := source.FilesChanged(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	s := &sizer{action: action, config: config, loadGitattributesFile: loadGitattributesFile}
+	size, err := s.Size(ctx, filesChanged)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	label := config.labelFor(size)
+	writeText(stdout, size, config.Labels, label)
+
+	if config.Limit.MaxLines > 0 && size.LinesCounted > config.Limit.MaxLines {
+		fmt.Fprintf(stderr, "%d lines changed, exceeding the limit of %d set by limit.max-lines\n",
+			size.LinesCounted, config.Limit.MaxLines)
+		if config.Limit.Mode != LimitModeWarn {
+			return 1
+		}
+	}
+	return 0
+}
+
+// writeText writes a plain text summary of the size of a change, for the
+// terminal.
+func writeText(w io.Writer, size prSize, labels []Label, label string) {
+	name := label
+	if name == "" {
+		name = "no label"
+	}
+	fmt.Fprintf(w, "%s: %d lines changed\n", name, size.LinesCounted)
+	fmt.Fprintf(w, "%d lines were changed across %d files, of which %d were ignored.\n",
+		size.LinesChanged, size.FilesChanged, size.FilesIgnored)
+	for _, l := range labels {
+		if l.Name == label && l.Guidance != "" {
+			fmt.Fprintf(w, "%s\n", l.Guidance)
+		}
+	}
+
+	if len(size.Files) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Additions\tDeletions\tCounted\t  File\n")
+	for _, f := range sortedFiles(size) {
+		fmt.Fprintf(tw, "%d\t%d\t%g\t", f.Additions, f.Deletions, f.LinesCounted)
+		if f.IgnoredReason != "" {
+			fmt.Fprintf(tw, "  %s (ignored: %s)\n", f.Filename, f.IgnoredReason)
+		} else {
+			fmt.Fprintf(tw, "  %s\n", f.Filename)
+		}
+	}
+	tw.Flush()
+}
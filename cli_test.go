@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestGitRepo creates a git repository with a main branch containing base
+// and a feature branch on top of it containing head, and changes into it.
+func newTestGitRepo(t *testing.T, base, head map[string]string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	// runSize reads the config through fs, which other tests replace
+	origFs := fs
+	fs = afero.Afero{Fs: afero.NewOsFs()}
+	t.Cleanup(func() { fs = origFs })
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+	commit := func(files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			assert.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+			assert.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+		}
+		git("add", "-A")
+		git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "commit")
+	}
+
+	git("init", "-q", "-b", "main")
+	commit(base)
+	git("checkout", "-q", "-b", "feature")
+	commit(head)
+}
+
+func TestRunSize(t *testing.T) {
+	newTestGitRepo(t,
+		map[string]string{
+			".gitattributes":              "gen/** linguist-generated\n",
+			".github/pr-size-labeler.yml": "exclude-paths: [\"go.sum\"]\nlimit:\n  max-lines: 5\nlabels:\n  - {name: size/S, min-lines: 0}\n  - {name: size/M, min-lines: 4}\n",
+			"main.go":                     "package main\n",
+		},
+		map[string]string{
+			"main.go":      "package main\n\nfunc main() {\n}\n",
+			"gen/api.go":   "a\nb\nc\nd\ne\nf\n",
+			"go.sum":       "x\ny\n",
+			"docs/more.md": "one\ntwo\n",
+		},
+	)
+
+	var stdout, stderr bytes.Buffer
+	code := runSize(context.Background(), []string{"--base", "main"}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	out := stdout.String()
+	assert.Contains(t, out, "size/M: 5 lines changed\n")
+	assert.Contains(t, out, "13 lines were changed across 4 files, of which 2 were ignored.")
+	assert.Regexp(t, `6\s+0\s+0\s+gen/api.go \(ignored: linguist-generated\)`, out)
+	assert.Regexp(t, "2\\s+0\\s+0\\s+go.sum \\(ignored: excluded by `go.sum`\\)", out)
+	assert.Regexp(t, `3\s+0\s+3\s+main.go`, out)
+}
+
+func TestRunSizeExceedsLimit(t *testing.T) {
+	newTestGitRepo(t,
+		map[string]string{
+			".github/pr-size-labeler.yml": "limit:\n  max-lines: 2\nlabels:\n  - {name: size/S, min-lines: 0}\n",
+		},
+		map[string]string{
+			"main.go": "a\nb\nc\n",
+		},
+	)
+
+	var stdout, stderr bytes.Buffer
+	code := runSize(context.Background(), []string{"--base", "main"}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "size/S: 3 lines changed\n")
+	assert.Equal(t, "3 lines changed, exceeding the limit of 2 set by limit.max-lines\n", stderr.String())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+	"golang.org/x/oauth2"
+)
+
+// defaultAPIURL is the URL of the GitHub.com API.
+const defaultAPIURL = "https://api.github.com"
+
+// newGitHubClientFunc returns a function creating clients for the GitHub API
+// at apiURL, e.g. https://github.example.com/api/v3 for GitHub Enterprise
+// Server, using the given HTTP client. If uploadURL is empty, it's derived from
+// apiURL.
+func newGitHubClientFunc(apiURL, uploadURL string) (func(*http.Client) *github.Client, error) {
+	if strings.TrimSuffix(apiURL, "/") == defaultAPIURL && uploadURL == "" {
+		return github.NewClient, nil
+	}
+	if uploadURL == "" {
+		uploadURL = defaultUploadURL(apiURL)
+	}
+	for _, u := range []string{apiURL, uploadURL} {
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid GitHub API URL: %q", u)
+		}
+	}
+
+	return func(httpClient *http.Client) *github.Client {
+		// The URLs were validated above, so this can't fail
+		client, _ := github.NewEnterpriseClient(apiURL, uploadURL, httpClient)
+		return client
+	}, nil
+}
+
+// defaultUploadURL returns the upload URL of the GitHub Enterprise Server API
+// at apiURL, which is next to it at /api/uploads.
+func defaultUploadURL(apiURL string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(apiURL, "/"), "/api/v3") + "/api/uploads/"
+}
+
+// newTokenClient returns a client created by newClient that authenticates
+// with the given token.
+func newTokenClient(ctx context.Context, newClient func(*http.Client) *github.Client, token string) *github.Client {
+	return newClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+}
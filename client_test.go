@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGitHubClientFunc(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		apiURL            string
+		uploadURL         string
+		expectedBaseURL   string
+		expectedUploadURL string
+		expectedErr       bool
+	}{
+		{
+			name:              "GitHub.com",
+			apiURL:            "https://api.github.com",
+			expectedBaseURL:   "https://api.github.com/",
+			expectedUploadURL: "https://uploads.github.com/",
+		},
+		{
+			name:              "GitHub Enterprise Server",
+			apiURL:            "https://github.example.com/api/v3",
+			expectedBaseURL:   "https://github.example.com/api/v3/",
+			expectedUploadURL: "https://github.example.com/api/uploads/",
+		},
+		{
+			name:              "GitHub Enterprise Server without the API path",
+			apiURL:            "https://github.example.com/",
+			expectedBaseURL:   "https://github.example.com/api/v3/",
+			expectedUploadURL: "https://github.example.com/api/uploads/",
+		},
+		{
+			name:              "explicit upload URL",
+			apiURL:            "https://github.example.com/api/v3/",
+			uploadURL:         "https://uploads.example.com/api/uploads/",
+			expectedBaseURL:   "https://github.example.com/api/v3/",
+			expectedUploadURL: "https://uploads.example.com/api/uploads/",
+		},
+		{
+			name:        "invalid URL",
+			apiURL:      "github.example.com",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			newClient, err := newGitHubClientFunc(tc.apiURL, tc.uploadURL)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			client := newTokenClient(t.Context(), newClient, "token")
+			assert.Equal(t, tc.expectedBaseURL, client.BaseURL.String())
+			assert.Equal(t, tc.expectedUploadURL, client.UploadURL.String())
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// pinnedLabelPrefix prefixes the marker label recording that a PR's size label
+// was pinned by a /size override command, e.g. size-pinned:size/L. Keeping it
+// on the PR means later runs, including backfills, keep the pinned label.
+const pinnedLabelPrefix = "size-pinned:"
+
+// commandUsage lists the /size commands, for replies to unknown commands.
+const commandUsage = "`/size recalc` recalculates the size label, `/size explain` explains it and `/size override <label>` pins it"
+
+// sizeCommand is a /size command from a PR comment, e.g. /size override L.
+type sizeCommand struct {
+	Name string
+	Args []string
+}
+
+// parseSizeCommand parses the /size command on the first line of a comment.
+// ok is false if the comment isn't a /size command.
+func parseSizeCommand(body string) (command sizeCommand, ok bool) {
+	line, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "/size" {
+		return sizeCommand{}, false
+	}
+
+	if len(fields) > 1 {
+		command.Name = strings.ToLower(fields[1])
+		command.Args = fields[2:]
+	}
+	return command, true
+}
+
+// RunCommand runs a /size command commented on the PR by author:
+//   - recalc recalculates the PR's size and relabels it like AddSizeLabel.
+//   - explain replies with the breakdown of the PR's size without relabeling
+//     it.
+//   - override pins the PR's size label to the given label, if author has
+//     write access to the repository. The pin is recorded with a marker label
+//     so that later runs keep it, and can be removed by removing that label.
+//
+// Mistakes, like unknown commands or labels, are replied to rather than
+// returned as errors so that they don't fail the workflow.
+func (l *GitHubPRSizeLabeler) RunCommand(ctx context.Context, command sizeCommand, author string) error {
+	l.action.Infof("Running /size %s command from %s", command.Name, author)
+
+	switch command.Name {
+	case "recalc":
+		return l.AddSizeLabel(ctx)
+	case "explain":
+		return l.explain(ctx, author)
+	case "override":
+		if len(command.Args) != 1 {
+			return l.reply(ctx, author, "`/size override` takes the label to pin, e.g. `/size override L`.")
+		}
+		return l.override(ctx, author, command.Args[0])
+	default:
+		return l.reply(ctx, author, fmt.Sprintf("unknown command. %s.", commandUsage))
+	}
+}
+
+// explain replies with the breakdown of the PR's size.
+func (l *GitHubPRSizeLabeler) explain(ctx context.Context, author string) error {
+	size, err := l.calculateSize(ctx)
+	if err != nil {
+		return err
+	}
+
+	label, _, err := l.selectLabel(ctx, size)
+	if err != nil {
+		return err
+	}
+	return l.reply(ctx, author, "here's how the size of this PR was calculated.\n\n"+
+		renderSummary(l.event.PRNumber(), size, l.config.Labels, label, l.checkLimit(size)))
+}
+
+// override pins the PR's size label to the configured label that name refers
+// to and relabels the PR.
+func (l *GitHubPRSizeLabeler) override(ctx context.Context, author, name string) error {
+	authorized, err := l.canOverride(ctx, author)
+	if err != nil {
+		return err
+	}
+	if !authorized {
+		l.action.Warningf("%s doesn't have write access to the repository, ignoring /size override", author)
+		return l.reply(ctx, author, "only collaborators with write access can override the size label.")
+	}
+
+	label, ok := l.config.resolveLabel(name)
+	if !ok {
+		names := make([]string, len(l.config.Labels))
+		for i, label := range l.config.Labels {
+			names[i] = "`" + label.Name + "`"
+		}
+		return l.reply(ctx, author, fmt.Sprintf("`%s` isn't a size label, expected one of %s.", name, strings.Join(names, ", ")))
+	}
+
+	pinLabel := pinnedLabelPrefix + label.Name
+	labels := []*github.Label{}
+	for _, prLabel := range l.event.PRLabels() {
+		if strings.HasPrefix(prLabel.GetName(), pinnedLabelPrefix) && prLabel.GetName() != pinLabel {
+			if err := l.removeLabel(ctx, prLabel.GetName()); err != nil {
+				return err
+			}
+			continue
+		}
+		labels = append(labels, prLabel)
+	}
+	if !l.prHasLabel(pinLabel) {
+		if err := l.addLabel(ctx, pinLabel); err != nil {
+			return err
+		}
+		labels = append(labels, &github.Label{Name: &pinLabel})
+	}
+
+	// The pin was just checked, so it needn't be looked up
+	relabeler := l.ForEvent(relabeledEvent{LabelEvent: l.event, labels: labels})
+	relabeler.verifiedPin = pinLabel
+	if err := relabeler.AddSizeLabel(ctx); err != nil {
+		return err
+	}
+	return l.reply(ctx, author, fmt.Sprintf("pinned the size label to `%s`. Remove the `%s` label to unpin it.", label.Name, pinLabel))
+}
+
+// canOverride returns whether user has write access to the repository and so
+// can override the PR's size label.
+func (l *GitHubPRSizeLabeler) canOverride(ctx context.Context, user string) (bool, error) {
+	level, _, err := l.repositories.GetPermissionLevel(ctx, l.owner, l.repo, user)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the permission level of %s: %w", user, err)
+	}
+	permission := level.GetPermission()
+	return permission == "admin" || permission == "write", nil
+}
+
+// pinnedLabel returns the size label the PR has been pinned to by a /size
+// override command, or an empty string if it hasn't been. Since anyone who can
+// label PRs, such as collaborators with triage access, can add the marker
+// label, pins are only honored if it was added by someone who can override the
+// size label or by a bot such as the labeler itself.
+func (l *GitHubPRSizeLabeler) pinnedLabel(ctx context.Context) (string, error) {
+	for _, prLabel := range l.event.PRLabels() {
+		name, ok := strings.CutPrefix(prLabel.GetName(), pinnedLabelPrefix)
+		if !ok {
+			continue
+		}
+		if _, ok := l.config.label(name); !ok {
+			l.action.Warningf("PR is pinned to %s, which isn't a configured size label, ignoring the pin", name)
+			continue
+		}
+		if prLabel.GetName() != l.verifiedPin {
+			authorized, err := l.pinAddedByOverrider(ctx, prLabel.GetName())
+			if err != nil {
+				return "", err
+			}
+			if !authorized {
+				l.action.Warningf("%s wasn't added by a collaborator with write access, ignoring the pin", prLabel.GetName())
+				continue
+			}
+		}
+		return name, nil
+	}
+	return "", nil
+}
+
+// pinAddedByOverrider returns whether the pin marker label was last added to
+// the PR by a bot, which the labeler is when it pins a label for a /size
+// override command, or by someone who can override the size label.
+func (l *GitHubPRSizeLabeler) pinAddedByOverrider(ctx context.Context, pinLabel string) (bool, error) {
+	var actor *github.User
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		events, resp, err := l.issues.ListIssueEvents(ctx, l.event.RepoOwner(), l.event.RepoName(), l.event.PRNumber(), opts)
+		if err != nil {
+			return false, fmt.Errorf("failed to list the events of PR #%d: %w", l.event.PRNumber(), err)
+		}
+		for _, event := range events {
+			if event.GetEvent() == "labeled" && event.GetLabel().GetName() == pinLabel {
+				actor = event.GetActor()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	if actor == nil {
+		return false, nil
+	}
+	if actor.GetType() == "Bot" {
+		return true, nil
+	}
+	return l.canOverride(ctx, actor.GetLogin())
+}
+
+// reply comments on the PR, mentioning author. Replies are truncated to the
+// longest comment GitHub accepts, since explanations of large PRs can be
+// longer.
+func (l *GitHubPRSizeLabeler) reply(ctx context.Context, author, body string) error {
+	body = truncate(fmt.Sprintf("@%s %s", author, body), maxCommentBody)
+	_, _, err := l.issues.CreateComment(ctx, l.event.RepoOwner(), l.event.RepoName(), l.event.PRNumber(), &github.IssueComment{
+		Body: &body,
+	})
+	return err
+}
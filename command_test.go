@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSizeCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedCommand sizeCommand
+		expectedOK      bool
+	}{
+		{
+			name:            "recalc",
+			body:            "/size recalc",
+			expectedCommand: sizeCommand{Name: "recalc", Args: []string{}},
+			expectedOK:      true,
+		},
+		{
+			name:            "override with a label",
+			body:            "  /size Override L\nIt's mostly generated",
+			expectedCommand: sizeCommand{Name: "override", Args: []string{"L"}},
+			expectedOK:      true,
+		},
+		{
+			name:            "no subcommand",
+			body:            "/size",
+			expectedCommand: sizeCommand{},
+			expectedOK:      true,
+		},
+		{
+			name:       "command not on the first line",
+			body:       "LGTM\n/size recalc",
+			expectedOK: false,
+		},
+		{
+			name:       "other command",
+			body:       "/sizes recalc",
+			expectedOK: false,
+		},
+		{
+			name:       "empty comment",
+			body:       "",
+			expectedOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			command, ok := parseSizeCommand(tc.body)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedCommand, command)
+		})
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                   string
+		command                sizeCommand
+		prLabels               []string
+		permission             string
+		expectedAdded          []string
+		expectedRemoved        []string
+		expectedReplyContains  string
+		expectedCommentsPosted int
+	}{
+		{
+			name:                   "recalc relabels the PR",
+			command:                sizeCommand{Name: "recalc"},
+			prLabels:               []string{"size/S"},
+			expectedAdded:          []string{"size/L"},
+			expectedRemoved:        []string{"size/S"},
+			expectedCommentsPosted: 0,
+		},
+		{
+			name:                   "explain replies with the breakdown without relabeling",
+			command:                sizeCommand{Name: "explain"},
+			prLabels:               []string{"size/S"},
+			expectedReplyContains:  "@octocat here's how the size of this PR was calculated.\n\n## PR Size\n\nPR #1 has **150** lines changed and is labeled `size/L`",
+			expectedCommentsPosted: 1,
+		},
+		{
+			name:                   "override pins the label",
+			command:                sizeCommand{Name: "override", Args: []string{"s"}},
+			prLabels:               []string{"size/L", "size-pinned:size/L"},
+			permission:             "write",
+			expectedAdded:          []string{"size-pinned:size/S", "size/S"},
+			expectedRemoved:        []string{"size-pinned:size/L", "size/L"},
+			expectedReplyContains:  "@octocat pinned the size label to `size/S`.",
+			expectedCommentsPosted: 1,
+		},
+		{
+			name:                   "override requires write access",
+			command:                sizeCommand{Name: "override", Args: []string{"S"}},
+			prLabels:               []string{"size/L"},
+			permission:             "read",
+			expectedReplyContains:  "@octocat only collaborators with write access can override the size label.",
+			expectedCommentsPosted: 1,
+		},
+		{
+			name:                   "override with an unknown label",
+			command:                sizeCommand{Name: "override", Args: []string{"XXL"}},
+			prLabels:               []string{"size/L"},
+			permission:             "admin",
+			expectedReplyContains:  "`XXL` isn't a size label, expected one of `size/S`, `size/L`.",
+			expectedCommentsPosted: 1,
+		},
+		{
+			name:                   "unknown command",
+			command:                sizeCommand{Name: "shrink"},
+			prLabels:               []string{"size/L"},
+			expectedReplyContains:  "@octocat unknown command.",
+			expectedCommentsPosted: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = []*github.CommitFile{{Filename: ptr("main.go"), Additions: ptr(150), Deletions: ptr(0), Patch: ptr("")}}
+			mockRepos := mocks.NewRepositoriesClient()
+			if tc.permission != "" {
+				mockRepos.Permissions["octocat"] = tc.permission
+			}
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", tc.prLabels),
+				Config{Labels: []Label{{Name: "size/S", MinLines: 0}, {Name: "size/L", MinLines: 100}}},
+				mockIssues,
+				mockPR,
+				mockRepos,
+			)
+
+			err := labeler.RunCommand(t.Context(), tc.command, "octocat")
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedAdded, mockIssues.AddedLabels)
+			assert.ElementsMatch(t, tc.expectedRemoved, mockIssues.RemovedLabels)
+			assert.Len(t, mockIssues.CreatedComments, tc.expectedCommentsPosted)
+			if tc.expectedReplyContains != "" {
+				assert.Contains(t, mockIssues.CreatedComments[0].GetBody(), tc.expectedReplyContains)
+			}
+		})
+	}
+}
+
+func TestRunCommandExplainLargePR(t *testing.T) {
+	t.Parallel()
+
+	mockIssues := mocks.NewIssuesClient()
+	mockPR := mocks.NewPullRequestsClient()
+	for i := range 5000 {
+		mockPR.FilesChanged = append(mockPR.FilesChanged, &github.CommitFile{
+			Filename:  ptr(fmt.Sprintf("generated/very/long/path/to/file%d.go", i)),
+			Additions: ptr(1),
+			Deletions: ptr(0),
+			Patch:     ptr(""),
+		})
+	}
+
+	labeler := newTestLabeler(
+		newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+		Config{Labels: []Label{{Name: "size/S", MinLines: 0}, {Name: "size/L", MinLines: 100}}},
+		mockIssues,
+		mockPR,
+		mocks.NewRepositoriesClient(),
+	)
+
+	err := labeler.RunCommand(t.Context(), sizeCommand{Name: "explain"}, "octocat")
+	assert.NoError(t, err)
+	assert.Len(t, mockIssues.CreatedComments, 1)
+	body := mockIssues.CreatedComments[0].GetBody()
+	assert.LessOrEqual(t, len(body), maxCommentBody)
+	assert.Contains(t, body, "_Truncated_")
+}
+
+func TestSelectLabelPinned(t *testing.T) {
+	t.Parallel()
+
+	pinnedBy := func(login, userType string) []*github.IssueEvent {
+		return []*github.IssueEvent{
+			{Event: ptr("labeled"), Actor: &github.User{Login: ptr("octocat"), Type: ptr("User")}, Label: &github.Label{Name: ptr("size/S")}},
+			{Event: ptr("labeled"), Actor: &github.User{Login: ptr(login), Type: ptr(userType)}, Label: &github.Label{Name: ptr("size-pinned:size/S")}},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		events        []*github.IssueEvent
+		expectedLabel string
+	}{
+		{
+			name:          "pinned by a collaborator with write access",
+			events:        pinnedBy("maintainer", "User"),
+			expectedLabel: "size/S",
+		},
+		{
+			name:          "pinned by a bot",
+			events:        pinnedBy("github-actions[bot]", "Bot"),
+			expectedLabel: "size/S",
+		},
+		{
+			name:          "pinned by a collaborator without write access",
+			events:        pinnedBy("triager", "User"),
+			expectedLabel: "size/L",
+		},
+		{
+			name:          "pin not found in the events",
+			events:        []*github.IssueEvent{},
+			expectedLabel: "size/L",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockIssues.Events = tc.events
+			mockRepos := mocks.NewRepositoriesClient()
+			mockRepos.Permissions["maintainer"] = "write"
+			mockRepos.Permissions["triager"] = "triage"
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{"size/S", "size-pinned:size/S"}),
+				Config{Labels: []Label{{Name: "size/S", MinLines: 0}, {Name: "size/L", MinLines: 100}}},
+				mockIssues,
+				mocks.NewPullRequestsClient(),
+				mockRepos,
+			)
+
+			newLabel, previousLabel, err := labeler.selectLabel(t.Context(), prSize{LinesCounted: 500})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedLabel, newLabel)
+			assert.Equal(t, "size/S", previousLabel)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// maxCommentBody is the longest comment body GitHub accepts.
+const maxCommentBody = 65536
+
+// UpdateComment creates or updates the PR comment explaining the PR's size.
+// If the PR is smaller than the comment's min-label, any existing comment is
+// deleted instead.
+func (l *GitHubPRSizeLabeler) UpdateComment(ctx context.Context, size prSize, label string) error {
+	existing, err := l.findComment(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !l.shouldComment(label) {
+		if existing == nil {
+			l.action.Infof("PR is smaller than %s, not commenting", l.config.Comment.MinLabel)
+			return nil
+		}
+		l.action.Infof("PR is smaller than %s, deleting size comment", l.config.Comment.MinLabel)
+		_, err := l.issues.DeleteComment(ctx, l.event.RepoOwner(), l.event.RepoName(), *existing.ID)
+		return err
+	}
+
+	// The file table of a large PR can be longer than GitHub accepts
+	body := truncate(renderComment(l.event.PRNumber(), size, l.config.Labels, label), maxCommentBody)
+
+	if existing == nil {
+		l.action.Infof("Creating size comment")
+		_, _, err := l.issues.CreateComment(ctx, l.event.RepoOwner(), l.event.RepoName(), l.event.PRNumber(), &github.IssueComment{
+			Body: &body,
+		})
+		return err
+	}
+
+	if existing.GetBody() == body {
+		l.action.Infof("Size comment is up to date")
+		return nil
+	}
+
+	l.action.Infof("Updating size comment")
+	_, _, err = l.issues.EditComment(ctx, l.event.RepoOwner(), l.event.RepoName(), *existing.ID, &github.IssueComment{
+		Body: &body,
+	})
+	return err
+}
+
+// shouldComment returns whether a PR with the given label is at least as
+// large as the comment's min-label.
+func (l *GitHubPRSizeLabeler) shouldComment(label string) bool {
+	if l.config.Comment.MinLabel == "" {
+		return true
+	}
+
+	minLabel, _ := l.config.label(l.config.Comment.MinLabel)
+	prLabel, ok := l.config.label(label)
+	return ok && prLabel.MinLines >= minLabel.MinLines
+}
+
+// findComment returns the PR comment previously created by UpdateComment, or
+// nil if there isn't one. Comments by others quoting the marker are ignored.
+func (l *GitHubPRSizeLabeler) findComment(ctx context.Context) (*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	for {
+		page, resp, err := l.issues.ListComments(ctx, l.event.RepoOwner(), l.event.RepoName(), l.event.PRNumber(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, comment := range page {
+			if strings.Contains(comment.GetBody(), commentMarker) && l.writtenByLabeler(ctx, comment.GetUser()) {
+				return comment, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// writtenByLabeler returns whether user is the labeler's own user: a bot, which
+// is who GitHub Actions and GitHub App tokens comment as, or the authenticated
+// user of other tokens. If the authenticated user can't be looked up, as with
+// GitHub App tokens, user isn't the labeler.
+func (l *GitHubPRSizeLabeler) writtenByLabeler(ctx context.Context, user *github.User) bool {
+	if user.GetType() == "Bot" {
+		return true
+	}
+
+	authenticated, _, err := l.users.Get(ctx, "")
+	if err != nil {
+		l.action.Debugf("Failed to get the authenticated user: %v", err)
+		return false
+	}
+	return user.GetLogin() == authenticated.GetLogin()
+}
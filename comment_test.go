@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateComment(t *testing.T) {
+	t.Parallel()
+
+	labels := []Label{
+		{Name: "size/S", MinLines: 0},
+		{Name: "size/L", MinLines: 100, Guidance: "Consider splitting this PR."},
+	}
+	size := prSize{LinesChanged: 150, LinesCounted: 150, FilesChanged: 1}
+	currentBody := renderComment(1, size, labels, "size/L")
+	bot := &github.User{Login: ptr("github-actions[bot]"), Type: ptr("Bot")}
+
+	tests := []struct {
+		name            string
+		existing        []*github.IssueComment
+		label           string
+		minLabel        string
+		expectedCreated int
+		expectedEdited  []int64
+		expectedDeleted []int64
+	}{
+		{
+			name:            "creates comment when there isn't one",
+			existing:        []*github.IssueComment{{ID: ptr(int64(1)), Body: ptr("LGTM")}},
+			label:           "size/L",
+			expectedCreated: 1,
+			expectedEdited:  []int64{},
+			expectedDeleted: []int64{},
+		},
+		{
+			name: "updates existing comment",
+			existing: []*github.IssueComment{
+				{ID: ptr(int64(1)), Body: ptr("LGTM")},
+				{ID: ptr(int64(2)), Body: ptr(commentMarker + "\nold"), User: bot},
+			},
+			label:           "size/L",
+			expectedCreated: 0,
+			expectedEdited:  []int64{2},
+			expectedDeleted: []int64{},
+		},
+		{
+			name: "updates existing comment by the authenticated user",
+			existing: []*github.IssueComment{
+				{ID: ptr(int64(2)), Body: ptr(commentMarker + "\nold"), User: &github.User{Login: ptr("labeler"), Type: ptr("User")}},
+			},
+			label:           "size/L",
+			expectedCreated: 0,
+			expectedEdited:  []int64{2},
+			expectedDeleted: []int64{},
+		},
+		{
+			name: "ignores comments by others quoting the marker",
+			existing: []*github.IssueComment{
+				{ID: ptr(int64(2)), Body: ptr("> " + commentMarker + "\n> old"), User: &github.User{Login: ptr("octocat"), Type: ptr("User")}},
+			},
+			label:           "size/S",
+			minLabel:        "size/L",
+			expectedCreated: 0,
+			expectedEdited:  []int64{},
+			expectedDeleted: []int64{},
+		},
+		{
+			name:            "leaves up to date comment alone",
+			existing:        []*github.IssueComment{{ID: ptr(int64(2)), Body: ptr(currentBody), User: bot}},
+			label:           "size/L",
+			expectedCreated: 0,
+			expectedEdited:  []int64{},
+			expectedDeleted: []int64{},
+		},
+		{
+			name:            "creates comment when PR is at least min-label",
+			existing:        []*github.IssueComment{},
+			label:           "size/L",
+			minLabel:        "size/L",
+			expectedCreated: 1,
+			expectedEdited:  []int64{},
+			expectedDeleted: []int64{},
+		},
+		{
+			name:            "doesn't comment when PR is smaller than min-label",
+			existing:        []*github.IssueComment{},
+			label:           "size/S",
+			minLabel:        "size/L",
+			expectedCreated: 0,
+			expectedEdited:  []int64{},
+			expectedDeleted: []int64{},
+		},
+		{
+			name:            "deletes comment when PR shrinks below min-label",
+			existing:        []*github.IssueComment{{ID: ptr(int64(2)), Body: ptr(currentBody), User: bot}},
+			label:           "size/S",
+			minLabel:        "size/L",
+			expectedCreated: 0,
+			expectedEdited:  []int64{},
+			expectedDeleted: []int64{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockIssues.Comments = tt.existing
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				Config{Labels: labels, Comment: CommentConfig{Enabled: true, MinLabel: tt.minLabel}},
+				mockIssues,
+				mocks.NewPullRequestsClient(),
+				mocks.NewRepositoriesClient(),
+			)
+
+			err := labeler.UpdateComment(t.Context(), size, tt.label)
+			assert.NoError(t, err)
+			assert.Len(t, mockIssues.CreatedComments, tt.expectedCreated)
+			for _, comment := range mockIssues.CreatedComments {
+				assert.Contains(t, *comment.Body, commentMarker)
+				assert.Contains(t, *comment.Body, "Consider splitting this PR.")
+			}
+
+			edited := []int64{}
+			for _, comment := range mockIssues.EditedComments {
+				edited = append(edited, *comment.ID)
+			}
+			assert.Equal(t, tt.expectedEdited, edited)
+			assert.Equal(t, tt.expectedDeleted, mockIssues.DeletedComments)
+		})
+	}
+}
+
+func TestUpdateCommentTruncatesLargePRs(t *testing.T) {
+	t.Parallel()
+
+	labels := []Label{{Name: "size/S", MinLines: 0}, {Name: "size/XXL", MinLines: 1000}}
+	size := prSize{LinesChanged: 5000, LinesCounted: 5000, FilesChanged: 5000}
+	for i := range 5000 {
+		size.Files = append(size.Files, fileSize{Filename: fmt.Sprintf("generated/very/long/path/to/file%d.go", i), Additions: 1, LinesCounted: 1})
+	}
+
+	mockIssues := mocks.NewIssuesClient()
+	labeler := newTestLabeler(
+		newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+		Config{Labels: labels, Comment: CommentConfig{Enabled: true}},
+		mockIssues,
+		mocks.NewPullRequestsClient(),
+		mocks.NewRepositoriesClient(),
+	)
+
+	err := labeler.UpdateComment(t.Context(), size, "size/XXL")
+	assert.NoError(t, err)
+	assert.Len(t, mockIssues.CreatedComments, 1)
+	body := mockIssues.CreatedComments[0].GetBody()
+	assert.LessOrEqual(t, len(body), maxCommentBody)
+	assert.Contains(t, body, commentMarker)
+	assert.Contains(t, body, "_Truncated_")
+}
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/google/go-github/v50/github"
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
@@ -13,6 +19,12 @@ type Label struct {
 	Color       string `yaml:"color"`
 	MinLines    int    `yaml:"min-lines"`
 	Description string `yaml:"description"`
+	// Guidance is included in the PR comment and job summary for PRs with
+	// this label, e.g. "Consider splitting this PR".
+	Guidance string `yaml:"guidance"`
+	// CheckConclusion is the conclusion of the check run for PRs with this
+	// label: success (the default), neutral or failure.
+	CheckConclusion string `yaml:"check-conclusion"`
 }
 
 func (l Label) Matches(label github.Label) bool {
@@ -21,9 +33,218 @@ func (l Label) Matches(label github.Label) bool {
 		label.Description != nil && *label.Description == l.Description
 }
 
+// CountMode controls how the additions and deletions of a file are combined
+// into its number of changed lines.
+type CountMode string
+
+const (
+	// CountModeSum counts additions + deletions. This is the default.
+	CountModeSum CountMode = "sum"
+	// CountModeMax counts max(additions, deletions), so modified lines are
+	// only counted once.
+	CountModeMax CountMode = "max"
+)
+
+// Weight scales the lines changed in files matching Path. The first matching
+// rule applies; files matching no rule count with a weight of 1.
+type Weight struct {
+	Path   string  `yaml:"path"`
+	Weight float64 `yaml:"weight"`
+	// AdditionWeight and DeletionWeight override the global weights of the
+	// same name for matching files.
+	AdditionWeight *float64 `yaml:"addition-weight"`
+	DeletionWeight *float64 `yaml:"deletion-weight"`
+}
+
+func (w *Weight) UnmarshalYAML(value *yaml.Node) error {
+	// Rules that only set addition-weight or deletion-weight shouldn't zero
+	// out matching files.
+	type rawWeight Weight
+	raw := rawWeight{Weight: 1}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	*w = Weight(raw)
+	return nil
+}
+
+// CommentConfig configures the PR comment explaining the PR's size.
+type CommentConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MinLabel is the smallest label a PR must have to be commented on. The
+	// comment is deleted if the PR shrinks below it. All PRs are commented
+	// on if it is empty.
+	MinLabel string `yaml:"min-label"`
+}
+
+// CheckRunConfig configures the check run reporting the PR's size.
+type CheckRunConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Name is the name of the check run. Defaults to "PR Size".
+	Name string `yaml:"name"`
+	// SkipLabels disables labeling PRs, for when the check run is enough.
+	SkipLabels bool `yaml:"skip-labels"`
+}
+
+// CommitStatusConfig configures the commit status reporting the PR's size.
+type CommitStatusConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Context is the context of the commit status. Defaults to "pr-size".
+	Context string `yaml:"context"`
+}
+
+// LimitMode controls what happens when a PR exceeds the size limit.
+type LimitMode string
+
+const (
+	// LimitModeFail fails the action so that branch protection can block
+	// the PR. This is the default.
+	LimitModeFail LimitMode = "fail"
+	// LimitModeWarn only emits a warning annotation.
+	LimitModeWarn LimitMode = "warn"
+)
+
+// LimitConfig configures a hard limit on the size of PRs.
+type LimitConfig struct {
+	// MaxLines is the largest number of lines a PR may change. There is no
+	// limit if it is zero.
+	MaxLines int       `yaml:"max-lines"`
+	Mode     LimitMode `yaml:"mode"`
+	// OverrideLabel lifts the limit for PRs with this label.
+	OverrideLabel string `yaml:"override-label"`
+	// OverrideMarker lifts the limit for PRs whose body contains it.
+	OverrideMarker string `yaml:"override-marker"`
+}
+
 type Config struct {
-	IgnoreLinguistGenerated bool    `yaml:"ignore-linguist-generated"`
-	Labels                  []Label `yaml:"labels"`
+	IgnoreLinguistGenerated     bool               `yaml:"ignore-linguist-generated"`
+	IgnoreLinguistVendored      bool               `yaml:"ignore-linguist-vendored"`
+	IgnoreLinguistDocumentation bool               `yaml:"ignore-linguist-documentation"`
+	IgnoreAttributes            []string           `yaml:"ignore-attributes"`
+	LocalGitattributesFallback  bool               `yaml:"local-gitattributes-fallback"`
+	ExcludePaths                []string           `yaml:"exclude-paths"`
+	Weights                     []Weight           `yaml:"weights"`
+	AdditionWeight              *float64           `yaml:"addition-weight"`
+	DeletionWeight              *float64           `yaml:"deletion-weight"`
+	CountMode                   CountMode          `yaml:"count-mode"`
+	Comment                     CommentConfig      `yaml:"comment"`
+	Limit                       LimitConfig        `yaml:"limit"`
+	CheckRun                    CheckRunConfig     `yaml:"check-run"`
+	CommitStatus                CommitStatusConfig `yaml:"commit-status"`
+	Labels                      []Label            `yaml:"labels"`
+}
+
+// label returns the configured label with the given name, if any.
+func (c Config) label(name string) (Label, bool) {
+	for _, l := range c.Labels {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return Label{}, false
+}
+
+// resolveLabel returns the configured label that name refers to, which is
+// either its full name or, ignoring case, the part after its last / or :,
+// e.g. L for size/L or size::L.
+func (c Config) resolveLabel(name string) (Label, bool) {
+	if l, ok := c.label(name); ok {
+		return l, true
+	}
+	for _, l := range c.Labels {
+		short := l.Name[strings.LastIndexAny(l.Name, "/:")+1:]
+		if strings.EqualFold(short, name) {
+			return l, true
+		}
+	}
+	return Label{}, false
+}
+
+// sortedLabels returns the configured size labels from largest to smallest.
+func (c Config) sortedLabels() []Label {
+	sizeLabels := make([]Label, len(c.Labels))
+	copy(sizeLabels, c.Labels)
+	sort.Slice(sizeLabels, func(i, j int) bool {
+		return sizeLabels[i].MinLines > sizeLabels[j].MinLines
+	})
+	return sizeLabels
+}
+
+// labelFor returns the size label for a change of the given size, which is the
+// largest label whose MinLines it reaches.
+func (c Config) labelFor(size prSize) string {
+	for _, label := range c.sortedLabels() {
+		if size.LinesCounted >= label.MinLines {
+			return label.Name
+		}
+	}
+	return ""
+}
+
+// matchExcludePath returns the first exclude-paths pattern that matches the
+// given file, if any.
+func (c Config) matchExcludePath(filename string) (string, bool) {
+	for _, pattern := range c.ExcludePaths {
+		if ok, _ := doublestar.Match(pattern, filename); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// matchWeight returns the index of the first weights rule that matches the
+// given file, if any.
+func (c Config) matchWeight(filename string) (int, bool) {
+	for i, rule := range c.Weights {
+		if ok, _ := doublestar.Match(rule.Path, filename); ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// ignoredAttributes returns the .gitattributes attributes that exclude a file
+// from the line count when set.
+func (c Config) ignoredAttributes() []string {
+	attrs := []string{}
+	if c.IgnoreLinguistGenerated {
+		attrs = append(attrs, "linguist-generated")
+	}
+	if c.IgnoreLinguistVendored {
+		attrs = append(attrs, "linguist-vendored")
+	}
+	if c.IgnoreLinguistDocumentation {
+		attrs = append(attrs, "linguist-documentation")
+	}
+	return append(attrs, c.IgnoreAttributes...)
+}
+
+// countLines returns the weighted number of lines changed for a file with the
+// given additions and deletions. rule is the weights rule matching the file,
+// or nil if there is none.
+func (c Config) countLines(additions, deletions int, rule *Weight) float64 {
+	additionWeight := valueOr(c.AdditionWeight, 1)
+	deletionWeight := valueOr(c.DeletionWeight, 1)
+	multiplier := 1.0
+	if rule != nil {
+		additionWeight = valueOr(rule.AdditionWeight, additionWeight)
+		deletionWeight = valueOr(rule.DeletionWeight, deletionWeight)
+		multiplier = rule.Weight
+	}
+
+	a := float64(additions) * additionWeight
+	d := float64(deletions) * deletionWeight
+	if c.CountMode == CountModeMax {
+		return math.Max(a, d) * multiplier
+	}
+	return (a + d) * multiplier
+}
+
+func valueOr[T any](v *T, fallback T) T {
+	if v == nil {
+		return fallback
+	}
+	return *v
 }
 
 func loadConfig(path string) (Config, error) {
@@ -32,7 +253,61 @@ func loadConfig(path string) (Config, error) {
 		return Config{}, err
 	}
 
-	c := Config{}
-	err = yaml.Unmarshal(configFile, &c)
-	return c, err
+	// linguist generated files have always been ignored, and .gitattributes
+	// used to be read from the working directory, so keep those as the
+	// defaults when the options aren't set.
+	c := Config{IgnoreLinguistGenerated: true, LocalGitattributesFallback: true}
+	if err := yaml.Unmarshal(configFile, &c); err != nil {
+		return c, err
+	}
+
+	for _, pattern := range c.ExcludePaths {
+		if !doublestar.ValidatePattern(pattern) {
+			return c, fmt.Errorf("invalid exclude-paths pattern: %q", pattern)
+		}
+	}
+
+	for _, rule := range c.Weights {
+		if !doublestar.ValidatePattern(rule.Path) {
+			return c, fmt.Errorf("invalid weights path pattern: %q", rule.Path)
+		}
+		if rule.Weight < 0 || valueOr(rule.AdditionWeight, 0) < 0 || valueOr(rule.DeletionWeight, 0) < 0 {
+			return c, fmt.Errorf("invalid weight for %q: must not be negative", rule.Path)
+		}
+	}
+
+	if valueOr(c.AdditionWeight, 0) < 0 || valueOr(c.DeletionWeight, 0) < 0 {
+		return c, fmt.Errorf("invalid addition-weight or deletion-weight: must not be negative")
+	}
+
+	switch c.CountMode {
+	case "", CountModeSum, CountModeMax:
+	default:
+		return c, fmt.Errorf("invalid count-mode %q: must be %q or %q", c.CountMode, CountModeSum, CountModeMax)
+	}
+
+	if c.Limit.MaxLines < 0 {
+		return c, fmt.Errorf("invalid limit max-lines %d: must not be negative", c.Limit.MaxLines)
+	}
+
+	switch c.Limit.Mode {
+	case "", LimitModeFail, LimitModeWarn:
+	default:
+		return c, fmt.Errorf("invalid limit mode %q: must be %q or %q", c.Limit.Mode, LimitModeFail, LimitModeWarn)
+	}
+
+	for _, label := range c.Labels {
+		switch label.CheckConclusion {
+		case "", "success", "neutral", "failure":
+		default:
+			return c, fmt.Errorf("invalid check-conclusion %q for label %s: must be success, neutral or failure", label.CheckConclusion, label.Name)
+		}
+	}
+
+	if c.Comment.MinLabel != "" {
+		if _, ok := c.label(c.Comment.MinLabel); !ok {
+			return c, fmt.Errorf("invalid comment min-label %q: no such label", c.Comment.MinLabel)
+		}
+	}
+	return c, nil
 }
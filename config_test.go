@@ -23,8 +23,18 @@ labels:
   description: "Less than 1000 lines"
 `
 
-func TestParsesConfig(t *testing.T) {
+// useMemMapFs replaces fs with an empty in-memory filesystem until the test
+// ends.
+func useMemMapFs(t *testing.T) {
+	t.Helper()
+
+	origFs := fs
 	fs = afero.Afero{Fs: afero.NewMemMapFs()}
+	t.Cleanup(func() { fs = origFs })
+}
+
+func TestParsesConfig(t *testing.T) {
+	useMemMapFs(t)
 
 	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(testConfigFile), 0644)
 	assert.NoError(t, err)
@@ -54,3 +64,326 @@ func TestParsesConfig(t *testing.T) {
 	}
 	assert.Equal(t, expectedLabels, config.Labels)
 }
+
+func TestParsesConfigDefaults(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(testConfigFile), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.True(t, config.IgnoreLinguistGenerated)
+	assert.Empty(t, config.ExcludePaths)
+}
+
+func TestParsesConfigExcludePaths(t *testing.T) {
+	useMemMapFs(t)
+
+	configFile := `
+ignore-linguist-generated: false
+exclude-paths:
+- "**/*.pb.go"
+- "vendor/**"
+`
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(configFile), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.False(t, config.IgnoreLinguistGenerated)
+	assert.Equal(t, []string{"**/*.pb.go", "vendor/**"}, config.ExcludePaths)
+}
+
+func TestParsesConfigInvalidExcludePath(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte("exclude-paths:\n- \"vendor/[\"\n"), 0644)
+	assert.NoError(t, err)
+
+	_, err = loadConfig(".github/pr-size-labeler.yml")
+	assert.Error(t, err)
+}
+
+func TestParsesConfigWeights(t *testing.T) {
+	useMemMapFs(t)
+
+	configFile := `
+weights:
+- path: "**/*_test.go"
+  weight: 0.5
+- path: "migrations/**"
+  weight: 2
+`
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(configFile), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, []Weight{
+		{Path: "**/*_test.go", Weight: 0.5},
+		{Path: "migrations/**", Weight: 2},
+	}, config.Weights)
+}
+
+func TestParsesConfigNegativeWeight(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte("weights:\n- path: \"docs/**\"\n  weight: -1\n"), 0644)
+	assert.NoError(t, err)
+
+	_, err = loadConfig(".github/pr-size-labeler.yml")
+	assert.Error(t, err)
+}
+
+func TestParsesConfigLineWeights(t *testing.T) {
+	useMemMapFs(t)
+
+	configFile := `
+addition-weight: 1
+deletion-weight: 0.1
+count-mode: max
+weights:
+- path: "migrations/**"
+  deletion-weight: 1
+`
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(configFile), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, ptr(1.0), config.AdditionWeight)
+	assert.Equal(t, ptr(0.1), config.DeletionWeight)
+	assert.Equal(t, CountModeMax, config.CountMode)
+	assert.Equal(t, []Weight{
+		{Path: "migrations/**", Weight: 1, DeletionWeight: ptr(1.0)},
+	}, config.Weights)
+}
+
+func TestParsesConfigInvalidCountMode(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte("count-mode: min\n"), 0644)
+	assert.NoError(t, err)
+
+	_, err = loadConfig(".github/pr-size-labeler.yml")
+	assert.Error(t, err)
+}
+
+func TestCountLines(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		config    Config
+		additions int
+		deletions int
+		rule      *Weight
+		expected  float64
+	}{
+		{
+			name:      "sums additions and deletions by default",
+			config:    Config{},
+			additions: 10,
+			deletions: 5,
+			expected:  15,
+		},
+		{
+			name:      "applies global addition and deletion weights",
+			config:    Config{AdditionWeight: ptr(1.0), DeletionWeight: ptr(0.1)},
+			additions: 10,
+			deletions: 50,
+			expected:  15,
+		},
+		{
+			name:      "max mode counts the larger of additions and deletions",
+			config:    Config{CountMode: CountModeMax},
+			additions: 10,
+			deletions: 5,
+			expected:  10,
+		},
+		{
+			name:      "max mode compares weighted values",
+			config:    Config{CountMode: CountModeMax, DeletionWeight: ptr(0.1)},
+			additions: 10,
+			deletions: 500,
+			expected:  50,
+		},
+		{
+			name:      "rule overrides global weights and applies its multiplier",
+			config:    Config{DeletionWeight: ptr(0.1)},
+			additions: 10,
+			deletions: 10,
+			rule:      &Weight{Path: "migrations/**", Weight: 2, DeletionWeight: ptr(1.0)},
+			expected:  40,
+		},
+		{
+			name:      "rule inherits global weights it doesn't override",
+			config:    Config{DeletionWeight: ptr(0.0)},
+			additions: 10,
+			deletions: 10,
+			rule:      &Weight{Path: "**/*_test.go", Weight: 0.5},
+			expected:  5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.InDelta(t, tt.expected, tt.config.countLines(tt.additions, tt.deletions, tt.rule), 0.0001)
+		})
+	}
+}
+
+func TestParsesConfigIgnoredAttributes(t *testing.T) {
+	useMemMapFs(t)
+
+	configFile := `
+ignore-linguist-vendored: true
+ignore-linguist-documentation: true
+ignore-attributes:
+- pr-size-ignore
+`
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(configFile), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"linguist-generated", "linguist-vendored", "linguist-documentation", "pr-size-ignore"}, config.ignoredAttributes())
+}
+
+func TestParsesConfigComment(t *testing.T) {
+	useMemMapFs(t)
+
+	configFile := `
+comment:
+  enabled: true
+  min-label: size/l
+labels:
+- name: size/s
+  min-lines: 0
+- name: size/l
+  min-lines: 100
+  guidance: Consider splitting this PR
+`
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(configFile), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, CommentConfig{Enabled: true, MinLabel: "size/l"}, config.Comment)
+	assert.Equal(t, "Consider splitting this PR", config.Labels[1].Guidance)
+}
+
+func TestParsesConfigInvalidCommentMinLabel(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(testConfigFile+"comment:\n  min-label: size/xxl\n"), 0644)
+	assert.NoError(t, err)
+
+	_, err = loadConfig(".github/pr-size-labeler.yml")
+	assert.Error(t, err)
+}
+
+func TestParsesConfigLimit(t *testing.T) {
+	useMemMapFs(t)
+
+	configFile := `
+limit:
+  max-lines: 2000
+  mode: warn
+  override-label: size/override-approved
+  override-marker: "<!-- size-override -->"
+`
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(configFile), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, LimitConfig{
+		MaxLines:       2000,
+		Mode:           LimitModeWarn,
+		OverrideLabel:  "size/override-approved",
+		OverrideMarker: "<!-- size-override -->",
+	}, config.Limit)
+}
+
+func TestParsesConfigInvalidLimitMode(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte("limit:\n  max-lines: 10\n  mode: block\n"), 0644)
+	assert.NoError(t, err)
+
+	_, err = loadConfig(".github/pr-size-labeler.yml")
+	assert.Error(t, err)
+}
+
+func TestParsesConfigCheckRun(t *testing.T) {
+	useMemMapFs(t)
+
+	configFile := `
+check-run:
+  enabled: true
+  name: Size
+  skip-labels: true
+labels:
+- name: size/xxl
+  min-lines: 1000
+  check-conclusion: failure
+`
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte(configFile), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, CheckRunConfig{Enabled: true, Name: "Size", SkipLabels: true}, config.CheckRun)
+	assert.Equal(t, "failure", config.Labels[0].CheckConclusion)
+}
+
+func TestParsesConfigInvalidCheckConclusion(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte("labels:\n- name: size/xxl\n  check-conclusion: cancelled\n"), 0644)
+	assert.NoError(t, err)
+
+	_, err = loadConfig(".github/pr-size-labeler.yml")
+	assert.Error(t, err)
+}
+
+func TestParsesConfigCommitStatus(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".github/pr-size-labeler.yml", []byte("commit-status:\n  enabled: true\n  context: size\n"), 0644)
+	assert.NoError(t, err)
+
+	config, err := loadConfig(".github/pr-size-labeler.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, CommitStatusConfig{Enabled: true, Context: "size"}, config.CommitStatus)
+}
+
+func TestResolveLabel(t *testing.T) {
+	t.Parallel()
+
+	config := Config{Labels: []Label{{Name: "size/S"}, {Name: "size::L"}, {Name: "XL"}}}
+
+	tests := []struct {
+		name          string
+		expectedLabel string
+		expectedOK    bool
+	}{
+		{name: "size/S", expectedLabel: "size/S", expectedOK: true},
+		{name: "S", expectedLabel: "size/S", expectedOK: true},
+		{name: "l", expectedLabel: "size::L", expectedOK: true},
+		{name: "xl", expectedLabel: "XL", expectedOK: true},
+		{name: "M", expectedOK: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			label, ok := config.resolveLabel(tc.name)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedLabel, label.Name)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// diffChangeSource reads the files changed from a unified diff, such as the
+// output of git diff or diff -u.
+type diffChangeSource struct {
+	r io.Reader
+}
+
+func (s *diffChangeSource) FilesChanged(ctx context.Context) ([]FileChange, error) {
+	return parseUnifiedDiff(s.r)
+}
+
+// parseUnifiedDiff parses a unified diff into the files it changes. Both git's
+// extended headers (diff --git, rename from/to, new/deleted file mode and
+// binary markers) and plain ---/+++ headers are understood.
+func parseUnifiedDiff(r io.Reader) ([]FileChange, error) {
+	files := []FileChange{}
+	var file *FileChange
+	var patch strings.Builder
+	// Lines left in the current hunk on each side
+	var oldLines, newLines int
+
+	flush := func() {
+		if file == nil {
+			return
+		}
+		file.Patch = strings.TrimSuffix(patch.String(), "\n")
+		files = append(files, *file)
+		file = nil
+		patch.Reset()
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNumber := 1; s.Scan(); lineNumber++ {
+		line := s.Text()
+
+		if oldLines > 0 || newLines > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				file.Additions++
+				newLines--
+			case strings.HasPrefix(line, "-"):
+				file.Deletions++
+				oldLines--
+			case strings.HasPrefix(line, " "), line == "":
+				oldLines--
+				newLines--
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				return nil, fmt.Errorf("invalid diff line %d: %q", lineNumber, line)
+			}
+			patch.WriteString(line + "\n")
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			file = &FileChange{Status: "modified", Filename: gitDiffFilename(strings.TrimPrefix(line, "diff --git "))}
+		case strings.HasPrefix(line, "--- ") && (file == nil || patch.Len() > 0):
+			// A plain diff without git's extended headers
+			flush()
+			file = &FileChange{Status: "modified"}
+			fallthrough
+		case strings.HasPrefix(line, "--- ") && file != nil:
+			if name := diffHeaderFilename(line[4:]); name != "" {
+				file.Filename = name
+			} else {
+				file.Status = "added"
+			}
+		case file == nil:
+			// Anything before the first file, like a commit message
+		case strings.HasPrefix(line, "+++ "):
+			if name := diffHeaderFilename(line[4:]); name != "" {
+				file.Filename = name
+			} else {
+				file.Status = "removed"
+			}
+		case strings.HasPrefix(line, "new file mode "):
+			file.Status = "added"
+		case strings.HasPrefix(line, "deleted file mode "):
+			file.Status = "removed"
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = "renamed"
+			file.PreviousFilename = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			file.Filename = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+			file.Binary = true
+		case strings.HasPrefix(line, "@@ "):
+			var err error
+			oldLines, newLines, err = parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid diff line %d: %w", lineNumber, err)
+			}
+			patch.WriteString(line + "\n")
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return files, nil
+}
+
+// parseHunkHeader returns the number of lines in a hunk on each side from its
+// header, like "@@ -1,5 +1,7 @@". A count is 1 if omitted.
+func parseHunkHeader(line string) (oldLines, newLines int, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, fmt.Errorf("invalid hunk header: %q", line)
+	}
+
+	count := func(r string) (int, error) {
+		_, n, found := strings.Cut(r[1:], ",")
+		if !found {
+			return 1, nil
+		}
+		return strconv.Atoi(n)
+	}
+	if oldLines, err = count(fields[1]); err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk header: %q", line)
+	}
+	if newLines, err = count(fields[2]); err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk header: %q", line)
+	}
+	return oldLines, newLines, nil
+}
+
+// gitDiffFilename returns the file name from the "a/name b/name" part of a
+// diff --git line. The name is ambiguous if it contains " b/", so the ---,
+// +++ and rename lines that follow take precedence when there are any.
+func gitDiffFilename(names string) string {
+	if half := (len(names) - 1) / 2; len(names)%2 == 1 && names[half] == ' ' &&
+		strings.TrimPrefix(names[:half], "a/") == strings.TrimPrefix(names[half+1:], "b/") {
+		return strings.TrimPrefix(names[half+1:], "b/")
+	}
+	if i := strings.LastIndex(names, " b/"); i >= 0 {
+		return names[i+3:]
+	}
+	return names
+}
+
+// diffHeaderFilename returns the file name from a ---/+++ line, without git's
+// a/ or b/ prefix or any timestamp, or an empty string for /dev/null.
+func diffHeaderFilename(header string) string {
+	name, _, _ := strings.Cut(header, "\t")
+	if name == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "b/") {
+		return name[2:]
+	}
+	return name
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		fixture       string
+		expectedFiles []FileChange
+	}{
+		{
+			name:    "git diff",
+			fixture: "testdata/git.diff",
+			expectedFiles: []FileChange{
+				{Filename: "added.go", Status: "added", Additions: 2},
+				{Filename: "docs/guide.md", Status: "modified", Additions: 1, Deletions: 1},
+				{Filename: "docs/moved.md", Status: "renamed", PreviousFilename: "moved.md"},
+				{Filename: "gen.pb.go", Status: "modified", Additions: 1},
+				{Filename: "logo.png", Status: "modified", Binary: true},
+				{Filename: "main.go", Status: "modified", Additions: 3, Deletions: 1},
+				{Filename: "new_name.go", Status: "renamed", Additions: 1, Deletions: 1, PreviousFilename: "old_name.go"},
+				{Filename: "removed.txt", Status: "removed", Deletions: 3},
+			},
+		},
+		{
+			name:    "plain diff",
+			fixture: "testdata/plain.diff",
+			expectedFiles: []FileChange{
+				{Filename: "x.txt", Status: "modified", Additions: 2, Deletions: 1},
+				{Filename: "y.txt", Status: "modified", Deletions: 1},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := os.Open(tc.fixture)
+			assert.NoError(t, err)
+			defer f.Close()
+
+			files, err := (&diffChangeSource{r: f}).FilesChanged(context.Background())
+			assert.NoError(t, err)
+
+			// Patches are checked separately
+			for i := range files {
+				files[i].Patch = ""
+			}
+			assert.Equal(t, tc.expectedFiles, files)
+		})
+	}
+}
+
+func TestParseUnifiedDiffPatch(t *testing.T) {
+	t.Parallel()
+
+	diff := "diff --git a/main.go b/main.go\n" +
+		"index 74b297b..0df7379 100644\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		" package main\n" +
+		"-// old\n" +
+		"+// new\n"
+
+	files, err := parseUnifiedDiff(strings.NewReader(diff))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "@@ -1,2 +1,2 @@\n package main\n-// old\n+// new", files[0].Patch)
+}
+
+func TestParseUnifiedDiffInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		diff string
+	}{
+		{
+			name: "invalid hunk header",
+			diff: "--- a/x\n+++ b/x\n@@ -1,a +1 @@\n",
+		},
+		{
+			name: "hunk shorter than its header",
+			diff: "--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\nnot a diff line\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseUnifiedDiff(strings.NewReader(tc.diff))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSizeDiffFixture(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/git.diff")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	filesChanged, err := (&diffChangeSource{r: f}).FilesChanged(context.Background())
+	assert.NoError(t, err)
+
+	gitattributes := map[string]string{"": "*.pb.go linguist-generated\n"}
+	s := &sizer{
+		action: githubactions.New(),
+		config: Config{IgnoreLinguistGenerated: true, ExcludePaths: []string{"docs/**"}},
+		loadGitattributesFile: func(_ context.Context, dir string) ([]byte, bool, error) {
+			content, ok := gitattributes[dir]
+			return []byte(content), ok, nil
+		},
+	}
+	size, err := s.Size(context.Background(), filesChanged)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 14, size.LinesChanged)
+	assert.Equal(t, 11, size.LinesCounted)
+	assert.Equal(t, 8, size.FilesChanged)
+	assert.Equal(t, 4, size.FilesIgnored)
+
+	reasons := map[string]string{}
+	for _, file := range size.Files {
+		reasons[file.Filename] = file.IgnoredReason
+	}
+	assert.Equal(t, map[string]string{
+		"added.go":      "",
+		"docs/guide.md": "excluded by `docs/**`",
+		"docs/moved.md": "excluded by `docs/**`",
+		"gen.pb.go":     "linguist-generated",
+		"logo.png":      "binary",
+		"main.go":       "",
+		"new_name.go":   "",
+		"removed.txt":   "",
+	}, reasons)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// dryRunPlan records the changes a dry run would have made.
+type dryRunPlan struct {
+	action *githubactions.Action
+
+	mu      sync.Mutex
+	entries []string
+}
+
+// record logs a change that would have been made and adds it to the plan.
+func (p *dryRunPlan) record(format string, args ...any) {
+	entry := fmt.Sprintf(format, args...)
+	p.action.Infof("[dry-run] Would %s", entry)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries = append(p.entries, entry)
+}
+
+// render renders the plan as Markdown for the job summary.
+func (p *dryRunPlan) render() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## Dry run\n\n")
+	if len(p.entries) == 0 {
+		fmt.Fprintf(&b, "No changes would have been made.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "The following changes would have been made:\n\n")
+	for _, entry := range p.entries {
+		fmt.Fprintf(&b, "- %s\n", entry)
+	}
+	return b.String()
+}
+
+// DryRun makes the labeler read everything as usual but log the labels,
+// comments, check runs and commit statuses it would create, update or delete
+// instead of changing them, and add the plan to the job summary. A PR
+// exceeding the limit only produces a warning.
+func (l *GitHubPRSizeLabeler) DryRun() {
+	l.plan = &dryRunPlan{action: l.action}
+	l.issues = &dryRunIssuesClient{IssuesClient: l.issues, plan: l.plan}
+	l.checks = &dryRunChecksClient{ChecksClient: l.checks, plan: l.plan}
+	l.repositories = &dryRunRepositoriesClient{RepositoriesClient: l.repositories, plan: l.plan}
+}
+
+// dryRunIssuesClient is an IssuesClient that passes reads through and records
+// writes in the plan.
+type dryRunIssuesClient struct {
+	IssuesClient
+	plan *dryRunPlan
+}
+
+func (c *dryRunIssuesClient) CreateLabel(ctx context.Context, owner, repo string, label *github.Label) (*github.Label, *github.Response, error) {
+	c.plan.record("create label `%s` with color `%s` and description %q", label.GetName(), label.GetColor(), label.GetDescription())
+	return label, &github.Response{}, nil
+}
+
+func (c *dryRunIssuesClient) EditLabel(ctx context.Context, owner, repo, name string, label *github.Label) (*github.Label, *github.Response, error) {
+	c.plan.record("update label `%s` to color `%s` and description %q", name, label.GetColor(), label.GetDescription())
+	return label, &github.Response{}, nil
+}
+
+func (c *dryRunIssuesClient) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error) {
+	added := make([]*github.Label, len(labels))
+	for i, label := range labels {
+		c.plan.record("add label `%s` to #%d", label, number)
+		added[i] = &github.Label{Name: github.String(label)}
+	}
+	return added, &github.Response{}, nil
+}
+
+func (c *dryRunIssuesClient) RemoveLabelForIssue(ctx context.Context, owner, repo string, number int, label string) (*github.Response, error) {
+	c.plan.record("remove label `%s` from #%d", label, number)
+	return &github.Response{}, nil
+}
+
+func (c *dryRunIssuesClient) CreateComment(ctx context.Context, owner, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	c.plan.record("comment on #%d", number)
+	return comment, &github.Response{}, nil
+}
+
+func (c *dryRunIssuesClient) EditComment(ctx context.Context, owner, repo string, commentID int64, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	c.plan.record("update comment %d", commentID)
+	return comment, &github.Response{}, nil
+}
+
+func (c *dryRunIssuesClient) DeleteComment(ctx context.Context, owner, repo string, commentID int64) (*github.Response, error) {
+	c.plan.record("delete comment %d", commentID)
+	return &github.Response{}, nil
+}
+
+// dryRunChecksClient is a ChecksClient that records the check runs it would
+// create in the plan.
+type dryRunChecksClient struct {
+	ChecksClient
+	plan *dryRunPlan
+}
+
+func (c *dryRunChecksClient) CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	c.plan.record("create check run %q on %s with conclusion `%s`", opts.Name, opts.HeadSHA, opts.GetConclusion())
+	return &github.CheckRun{Name: &opts.Name, HeadSHA: &opts.HeadSHA}, &github.Response{}, nil
+}
+
+// dryRunRepositoriesClient is a RepositoriesClient that passes reads through
+// and records the commit statuses it would create in the plan.
+type dryRunRepositoriesClient struct {
+	RepositoriesClient
+	plan *dryRunPlan
+}
+
+func (c *dryRunRepositoriesClient) CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error) {
+	c.plan.record("set commit status %q on %s to `%s`", status.GetContext(), ref, status.GetState())
+	return status, &github.Response{}, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	summaryPath := filepath.Join(t.TempDir(), "summary")
+	action := githubactions.New(githubactions.WithGetenv(func(key string) string {
+		if key == "GITHUB_STEP_SUMMARY" {
+			return summaryPath
+		}
+		return ""
+	}))
+
+	mockIssues := mocks.NewIssuesClient()
+	mockIssues.Labels["size/S"] = &github.Label{Name: ptr("size/S"), Color: ptr("000000"), Description: ptr("")}
+	mockIssues.Comments = []*github.IssueComment{{ID: ptr(int64(42)), Body: ptr(commentMarker + "\nstale"), User: &github.User{Login: ptr("github-actions[bot]"), Type: ptr("Bot")}}}
+	mockPR := mocks.NewPullRequestsClient()
+	mockPR.FilesChanged = []*github.CommitFile{
+		{Filename: ptr("main.go"), Additions: ptr(150), Deletions: ptr(0)},
+	}
+	mockRepos := mocks.NewRepositoriesClient()
+	mockChecks := mocks.NewChecksClient()
+
+	labeler := newTestLabeler(
+		newTestGitHubPullRequestEvent(1, "repo", "owner", []string{"size/S"}),
+		Config{
+			Comment:      CommentConfig{Enabled: true},
+			CheckRun:     CheckRunConfig{Enabled: true},
+			CommitStatus: CommitStatusConfig{Enabled: true},
+			Limit:        LimitConfig{MaxLines: 100},
+			Labels: []Label{
+				{Name: "size/S", Color: "00ff00", MinLines: 0},
+				{Name: "size/L", Color: "ff0000", MinLines: 100},
+			},
+		},
+		mockIssues,
+		mockPR,
+		mockRepos,
+	)
+	labeler.action = action
+	labeler.checks = mockChecks
+	labeler.DryRun()
+
+	assert.NoError(t, labeler.CreateSizeLabels(t.Context()))
+	assert.NoError(t, labeler.AddSizeLabel(t.Context()))
+
+	assert.Empty(t, mockIssues.CreatedLabels)
+	assert.Empty(t, mockIssues.EditedLabels)
+	assert.Empty(t, mockIssues.AddedLabels)
+	assert.Empty(t, mockIssues.RemovedLabels)
+	assert.Empty(t, mockIssues.EditedComments)
+	assert.Empty(t, mockChecks.CreatedCheckRuns)
+	assert.Empty(t, mockRepos.CreatedStatuses)
+
+	assert.Equal(t, []string{
+		"update label `size/S` to color `00ff00` and description \"\"",
+		"create label `size/L` with color `ff0000` and description \"\"",
+		"remove label `size/S` from #1",
+		"add label `size/L` to #1",
+		"update comment 42",
+		"create check run \"PR Size\" on " + testHeadSHA + " with conclusion `failure`",
+		"set commit status \"pr-size\" on " + testHeadSHA + " to `failure`",
+	}, labeler.plan.entries)
+
+	summary, err := os.ReadFile(summaryPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(summary), "## Dry run\n\nThe following changes would have been made:\n\n- update label `size/S`")
+	assert.Contains(t, string(summary), "- add label `size/L` to #1\n")
+}
+
+func TestDryRunPlanRenderEmpty(t *testing.T) {
+	t.Parallel()
+
+	plan := &dryRunPlan{action: githubactions.New()}
+	assert.Equal(t, "## Dry run\n\nNo changes would have been made.\n", plan.render())
+}
@@ -12,6 +12,14 @@ type LabelEvent interface {
 	PRNumber() int
 	// Current labels on the pull request
 	PRLabels() []*github.Label
+	// The SHA of the pull request's base commit
+	BaseSHA() string
+	// The body of the pull request
+	PRBody() string
+	// The SHA of the pull request's head commit
+	HeadSHA() string
+	// The number of files changed in the pull request
+	PRChangedFiles() int
 }
 
 // PullRequestEvent represents a GitHub Pull Request event.
@@ -40,6 +48,26 @@ func (e PullRequestEvent) RepoOwner() string {
 	return *e.event.PullRequest.Base.Repo.Owner.Login
 }
 
+// BaseSHA returns the SHA of the pull request's base commit.
+func (e PullRequestEvent) BaseSHA() string {
+	return *e.event.PullRequest.Base.SHA
+}
+
+// PRBody returns the body of the pull request, which may be empty.
+func (e PullRequestEvent) PRBody() string {
+	return e.event.PullRequest.GetBody()
+}
+
+// HeadSHA returns the SHA of the pull request's head commit.
+func (e PullRequestEvent) HeadSHA() string {
+	return *e.event.PullRequest.Head.SHA
+}
+
+// PRChangedFiles returns the number of files changed in the pull request.
+func (e PullRequestEvent) PRChangedFiles() int {
+	return e.event.PullRequest.GetChangedFiles()
+}
+
 // PullRequestTargetEvent represents a GitHub Pull Request Target event.
 // It implements the LabelEvent interface.
 type PullRequestTargetEvent struct {
@@ -65,3 +93,158 @@ func (e PullRequestTargetEvent) RepoName() string {
 func (e PullRequestTargetEvent) RepoOwner() string {
 	return *e.event.PullRequest.Base.Repo.Owner.Login
 }
+
+// BaseSHA returns the SHA of the pull request's base commit.
+func (e PullRequestTargetEvent) BaseSHA() string {
+	return *e.event.PullRequest.Base.SHA
+}
+
+// PRBody returns the body of the pull request, which may be empty.
+func (e PullRequestTargetEvent) PRBody() string {
+	return e.event.PullRequest.GetBody()
+}
+
+// HeadSHA returns the SHA of the pull request's head commit.
+func (e PullRequestTargetEvent) HeadSHA() string {
+	return *e.event.PullRequest.Head.SHA
+}
+
+// PRChangedFiles returns the number of files changed in the pull request.
+func (e PullRequestTargetEvent) PRChangedFiles() int {
+	return e.event.PullRequest.GetChangedFiles()
+}
+
+// FetchedPullRequest represents a pull request fetched from the GitHub API
+// rather than read from an event payload, so that PRs can be labeled outside
+// of pull request events. It implements the LabelEvent interface.
+type FetchedPullRequest struct {
+	pr *github.PullRequest
+}
+
+// PRLabels returns the labels on the pull request.
+func (e FetchedPullRequest) PRLabels() []*github.Label {
+	return e.pr.Labels
+}
+
+// PRNumber returns the number of the pull request.
+func (e FetchedPullRequest) PRNumber() int {
+	return *e.pr.Number
+}
+
+// RepoName returns the name of the repository.
+func (e FetchedPullRequest) RepoName() string {
+	return *e.pr.Base.Repo.Name
+}
+
+// RepoOwner returns the owner of the repository.
+func (e FetchedPullRequest) RepoOwner() string {
+	return *e.pr.Base.Repo.Owner.Login
+}
+
+// BaseSHA returns the SHA of the pull request's base commit.
+func (e FetchedPullRequest) BaseSHA() string {
+	return *e.pr.Base.SHA
+}
+
+// PRBody returns the body of the pull request, which may be empty.
+func (e FetchedPullRequest) PRBody() string {
+	return e.pr.GetBody()
+}
+
+// HeadSHA returns the SHA of the pull request's head commit.
+func (e FetchedPullRequest) HeadSHA() string {
+	return *e.pr.Head.SHA
+}
+
+// PRChangedFiles returns the number of files changed in the pull request.
+// Pull requests listed rather than fetched individually don't include it, in
+// which case it's 0.
+func (e FetchedPullRequest) PRChangedFiles() int {
+	return e.pr.GetChangedFiles()
+}
+
+// PullRequestComment represents a comment made on a pull request by an
+// issue_comment event, along with the pull request, which is fetched from the
+// GitHub API since the event doesn't include it. It implements the LabelEvent
+// interface.
+type PullRequestComment struct {
+	FetchedPullRequest
+	event github.IssueCommentEvent
+}
+
+// CommentAction returns the action of the event, e.g. created or edited.
+func (e PullRequestComment) CommentAction() string {
+	return e.event.GetAction()
+}
+
+// CommentBody returns the body of the comment.
+func (e PullRequestComment) CommentBody() string {
+	return e.event.GetComment().GetBody()
+}
+
+// CommentAuthor returns the login of the user who made the comment.
+func (e PullRequestComment) CommentAuthor() string {
+	return e.event.GetComment().GetUser().GetLogin()
+}
+
+// relabeledEvent is a LabelEvent whose labels have been changed since it was
+// read, so that they don't need to be fetched again.
+type relabeledEvent struct {
+	LabelEvent
+	labels []*github.Label
+}
+
+// PRLabels returns the current labels on the pull request.
+func (e relabeledEvent) PRLabels() []*github.Label {
+	return e.labels
+}
+
+// MergeGroupEvent represents a PR being checked in a merge queue by a
+// merge_group event, along with the PR, which is fetched from the GitHub API
+// since the event only names it in its head ref. The merge group's commits
+// are used as the base and head so that the size is reported on the commit the
+// merge queue checks. It implements the LabelEvent interface.
+type MergeGroupEvent struct {
+	event github.MergeGroupEvent
+	pr    *github.PullRequest
+}
+
+// PRLabels returns the labels on the pull request.
+func (e MergeGroupEvent) PRLabels() []*github.Label {
+	return e.pr.Labels
+}
+
+// PRNumber returns the number of the pull request.
+func (e MergeGroupEvent) PRNumber() int {
+	return *e.pr.Number
+}
+
+// RepoName returns the name of the repository.
+func (e MergeGroupEvent) RepoName() string {
+	return *e.event.Repo.Name
+}
+
+// RepoOwner returns the owner of the repository.
+func (e MergeGroupEvent) RepoOwner() string {
+	return *e.event.Repo.Owner.Login
+}
+
+// BaseSHA returns the SHA of the merge group's parent commit.
+func (e MergeGroupEvent) BaseSHA() string {
+	return *e.event.MergeGroup.BaseSHA
+}
+
+// PRBody returns the body of the pull request, which may be empty.
+func (e MergeGroupEvent) PRBody() string {
+	return e.pr.GetBody()
+}
+
+// HeadSHA returns the SHA of the merge group's commit.
+func (e MergeGroupEvent) HeadSHA() string {
+	return *e.event.MergeGroup.HeadSHA
+}
+
+// PRChangedFiles returns the number of files changed in the pull request.
+func (e MergeGroupEvent) PRChangedFiles() int {
+	return e.pr.GetChangedFiles()
+}
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"errors"
+	"io"
 	"testing"
 
 	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/sethvargo/go-githubactions"
 	"github.com/stretchr/testify/assert"
 )
 
+const (
+	testBaseSHA = "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+	testHeadSHA = "e5bd3914e2e596debea16f433f57875b5b90bcd6"
+	testPRBody  = "This PR changes some things"
+)
+
 type LabelEventExpected struct {
 	repoName  string
 	repoOwner string
@@ -24,8 +34,13 @@ func newTestGitHubPullRequestEvent(number int, repoName, repoOwner string, label
 		event: github.PullRequestEvent{
 			PullRequest: &github.PullRequest{
 				Number: ptr(number),
+				Body:   ptr(testPRBody),
 				Labels: labelObjs,
+				Head: &github.PullRequestBranch{
+					SHA: ptr(testHeadSHA),
+				},
 				Base: &github.PullRequestBranch{
+					SHA: ptr(testBaseSHA),
 					Repo: &github.Repository{
 						Name:  ptr(repoName),
 						Owner: &github.User{Login: ptr(repoOwner)},
@@ -103,6 +118,9 @@ func TestPullRequestEvent(t *testing.T) {
 			assert.Equal(t, tt.expected.repoName, tt.event.RepoName())
 			assert.Equal(t, tt.expected.repoOwner, tt.event.RepoOwner())
 			assert.Equal(t, tt.expected.prNumber, tt.event.PRNumber())
+			assert.Equal(t, testBaseSHA, tt.event.BaseSHA())
+			assert.Equal(t, testPRBody, tt.event.PRBody())
+			assert.Equal(t, testHeadSHA, tt.event.HeadSHA())
 
 			labels := tt.event.PRLabels()
 			assert.Len(t, labels, len(tt.expected.labels))
@@ -123,8 +141,13 @@ func newTestGitHubPullRequestTargetEvent(number int, repoName, repoOwner string,
 		event: github.PullRequestTargetEvent{
 			PullRequest: &github.PullRequest{
 				Number: ptr(number),
+				Body:   ptr(testPRBody),
 				Labels: labelObjs,
+				Head: &github.PullRequestBranch{
+					SHA: ptr(testHeadSHA),
+				},
 				Base: &github.PullRequestBranch{
+					SHA: ptr(testBaseSHA),
 					Repo: &github.Repository{
 						Name:  ptr(repoName),
 						Owner: &github.User{Login: ptr(repoOwner)},
@@ -202,6 +225,9 @@ func TestPullRequestTargetEvent(t *testing.T) {
 			assert.Equal(t, tt.expected.repoName, tt.event.RepoName())
 			assert.Equal(t, tt.expected.repoOwner, tt.event.RepoOwner())
 			assert.Equal(t, tt.expected.prNumber, tt.event.PRNumber())
+			assert.Equal(t, testBaseSHA, tt.event.BaseSHA())
+			assert.Equal(t, testPRBody, tt.event.PRBody())
+			assert.Equal(t, testHeadSHA, tt.event.HeadSHA())
 
 			labels := tt.event.PRLabels()
 			assert.Len(t, labels, len(tt.expected.labels))
@@ -215,3 +241,117 @@ func TestPullRequestTargetEvent(t *testing.T) {
 func ptr[T any](v T) *T {
 	return &v
 }
+
+func TestGetPREvent(t *testing.T) {
+	tests := []struct {
+		name             string
+		eventName        string
+		payload          string
+		prNumberInput    string
+		expectedPRNumber int
+		expectedErr      string
+		// notApplicable is whether the error is errNotApplicable, which
+		// doesn't fail the action.
+		notApplicable bool
+	}{
+		{
+			name:             "pull request event",
+			eventName:        "pull_request",
+			payload:          `{"pull_request": {"number": 1, "base": {"repo": {"name": "repo", "owner": {"login": "owner"}}}}}`,
+			expectedPRNumber: 1,
+		},
+		{
+			name:             "comment on a PR",
+			eventName:        "issue_comment",
+			payload:          `{"issue": {"number": 2, "pull_request": {"url": "https://api.github.com/repos/owner/repo/pulls/2"}}, "repository": {"name": "repo", "owner": {"login": "owner"}}}`,
+			expectedPRNumber: 2,
+		},
+		{
+			name:          "comment on an issue",
+			eventName:     "issue_comment",
+			payload:       `{"issue": {"number": 3}, "repository": {"name": "repo", "owner": {"login": "owner"}}}`,
+			expectedErr:   "labeling is not applicable to issue #3, which isn't a pull request",
+			notApplicable: true,
+		},
+		{
+			name:             "pr-number input",
+			eventName:        "workflow_dispatch",
+			payload:          `{}`,
+			prNumberInput:    "2",
+			expectedPRNumber: 2,
+		},
+		{
+			name:          "pr-number input that isn't a number",
+			eventName:     "workflow_dispatch",
+			payload:       `{}`,
+			prNumberInput: "two",
+			expectedErr:   `invalid pr-number input: "two"`,
+		},
+		{
+			name:          "pr-number input for a missing PR",
+			eventName:     "workflow_dispatch",
+			payload:       `{}`,
+			prNumberInput: "4",
+			expectedErr:   "failed to fetch PR #4",
+		},
+		{
+			name:             "merge group for a PR",
+			eventName:        "merge_group",
+			payload:          `{"action": "checks_requested", "merge_group": {"head_ref": "refs/heads/gh-readonly-queue/main/pr-2-6dcb09b5b57875f334f61aebed695e2e4193db5e", "head_sha": "abc", "base_sha": "def"}, "repository": {"name": "repo", "owner": {"login": "owner"}}}`,
+			expectedPRNumber: 2,
+		},
+		{
+			name:          "merge group that isn't for a PR",
+			eventName:     "merge_group",
+			payload:       `{"action": "checks_requested", "merge_group": {"head_ref": "refs/heads/main"}, "repository": {"name": "repo", "owner": {"login": "owner"}}}`,
+			expectedErr:   "labeling is not applicable to merge group refs/heads/main, which isn't for a PR",
+			notApplicable: true,
+		},
+		{
+			name:          "destroyed merge group",
+			eventName:     "merge_group",
+			payload:       `{"action": "destroyed", "merge_group": {"head_ref": "refs/heads/gh-readonly-queue/main/pr-2-6dcb09b5b57875f334f61aebed695e2e4193db5e"}, "repository": {"name": "repo", "owner": {"login": "owner"}}}`,
+			expectedErr:   "labeling is not applicable to merge_group destroyed events",
+			notApplicable: true,
+		},
+		{
+			name:        "other event",
+			eventName:   "push",
+			payload:     `{}`,
+			expectedErr: "event *github.PushEvent is not a pull request event",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useMemMapFs(t)
+			err := fs.WriteFile("/event.json", []byte(tc.payload), 0644)
+			assert.NoError(t, err)
+
+			env := map[string]string{
+				"GITHUB_EVENT_NAME": tc.eventName,
+				"GITHUB_EVENT_PATH": "/event.json",
+				"GITHUB_REPOSITORY": "owner/repo",
+				"INPUT_PR-NUMBER":   tc.prNumberInput,
+			}
+			action := githubactions.New(
+				githubactions.WithGetenv(func(key string) string { return env[key] }),
+				githubactions.WithWriter(io.Discard),
+			)
+
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.PullRequests = []*github.PullRequest{newTestPullRequest(2, "open", []string{"size/S"}, 0)}
+
+			event, err := getPREvent(t.Context(), action, mockPR)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				assert.Equal(t, tc.notApplicable, errors.Is(err, errNotApplicable))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPRNumber, event.PRNumber())
+			assert.Equal(t, "owner", event.RepoOwner())
+			assert.Equal(t, "repo", event.RepoName())
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// gitRepo runs git commands in a local repository.
+type gitRepo struct {
+	// dir is the repository's working directory, or an empty string for the
+	// current directory.
+	dir string
+}
+
+// run runs git with the given arguments and returns its standard output.
+func (g gitRepo) run(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = g.dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// mergeBase returns the best common ancestor of base and head, which is what
+// a PR from head into base is compared against.
+func (g gitRepo) mergeBase(ctx context.Context, base, head string) (string, error) {
+	out, err := g.run(ctx, "merge-base", base, head)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// filesChanged returns the files changed between base and head, detecting
+// renames like GitHub does. numstat doesn't include patches, so they're left
+// empty.
+func (g gitRepo) filesChanged(ctx context.Context, base, head string) ([]FileChange, error) {
+	out, err := g.run(ctx, "diff", "--numstat", "-z", "-M", base, head)
+	if err != nil {
+		return nil, err
+	}
+	return parseNumstat(out)
+}
+
+// gitattributesLoader returns a gitattributesLoader that reads .gitattributes
+// files at the given revision.
+func (g gitRepo) gitattributesLoader(rev string) gitattributesLoader {
+	return func(ctx context.Context, dir string) ([]byte, bool, error) {
+		object := rev + ":" + path.Join(dir, ".gitattributes")
+
+		if _, err := g.run(ctx, "cat-file", "-e", object); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+
+		content, err := g.run(ctx, "cat-file", "blob", object)
+		if err != nil {
+			return nil, false, err
+		}
+		return content, true, nil
+	}
+}
+
+// parseNumstat parses the output of git diff --numstat -z. Each file is
+// "additions\tdeletions\tpath\x00", or "additions\tdeletions\t\x00old\x00new\x00"
+// if it was renamed. Binary files have "-" for their additions and deletions.
+func parseNumstat(out []byte) ([]FileChange, error) {
+	files := []FileChange{}
+	if len(out) == 0 {
+		return files, nil
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		line := fields[i]
+		stat := strings.SplitN(line, "\t", 3)
+		if len(stat) != 3 {
+			return nil, fmt.Errorf("invalid numstat line: %q", line)
+		}
+
+		file := FileChange{
+			Filename: stat[2],
+			Status:   "modified",
+		}
+		if stat[2] == "" {
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("invalid numstat rename: %q", line)
+			}
+			file.PreviousFilename = fields[i+1]
+			file.Filename = fields[i+2]
+			file.Status = "renamed"
+			i += 2
+		}
+
+		if stat[0] == "-" && stat[1] == "-" {
+			file.Binary = true
+			files = append(files, file)
+			continue
+		}
+
+		additions, err := strconv.Atoi(stat[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid numstat additions: %q", line)
+		}
+		deletions, err := strconv.Atoi(stat[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid numstat deletions: %q", line)
+		}
+		file.Additions = additions
+		file.Deletions = deletions
+		files = append(files, file)
+	}
+	return files, nil
+}
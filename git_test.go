@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumstat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		output        string
+		expectedFiles []FileChange
+		expectedErr   bool
+	}{
+		{
+			name:          "no changes",
+			output:        "",
+			expectedFiles: []FileChange{},
+		},
+		{
+			name:   "modified files",
+			output: "10\t2\tmain.go\x000\t5\tdocs/README.md\x00",
+			expectedFiles: []FileChange{
+				{Filename: "main.go", Status: "modified", Additions: 10, Deletions: 2},
+				{Filename: "docs/README.md", Status: "modified", Additions: 0, Deletions: 5},
+			},
+		},
+		{
+			name:   "renamed file",
+			output: "1\t1\t\x00old.go\x00new.go\x003\t0\tother.go\x00",
+			expectedFiles: []FileChange{
+				{Filename: "new.go", PreviousFilename: "old.go", Status: "renamed", Additions: 1, Deletions: 1},
+				{Filename: "other.go", Status: "modified", Additions: 3, Deletions: 0},
+			},
+		},
+		{
+			name:   "binary file",
+			output: "-\t-\tlogo.png\x00",
+			expectedFiles: []FileChange{
+				{Filename: "logo.png", Status: "modified", Binary: true},
+			},
+		},
+		{
+			name:        "malformed line",
+			output:      "main.go\x00",
+			expectedErr: true,
+		},
+		{
+			name:        "truncated rename",
+			output:      "1\t1\t\x00old.go\x00",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			files, err := parseNumstat([]byte(tc.output))
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedFiles, files)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	attributeSet   = "true"
+	attributeUnset = "false"
+)
+
+// attributes holds the rules from one or more .gitattributes files. Rules are
+// ordered by precedence, lowest first: a file's rules come after those of the
+// files in its parent directories and, within a file, later lines come after
+// earlier ones.
+type attributes struct {
+	rules []attributeRule
+}
+
+type attributeRule struct {
+	// dir is the directory of the .gitattributes file the rule is from, or
+	// an empty string for the root of the repository.
+	dir     string
+	pattern string
+	values  map[string]string
+}
+
+// parseAttributes parses the contents of the .gitattributes file in dir. Set
+// attributes (attr) have the value "true", unset attributes (-attr) have the
+// value "false" and unspecified attributes (!attr) are recorded with an empty
+// value so they can override earlier lines.
+func parseAttributes(dir string, content []byte) (*attributes, error) {
+	a := &attributes{}
+
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		// Skip blank lines, comments and macro definitions
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[attr]") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		// Negative patterns are not allowed in .gitattributes files
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+
+		rule := attributeRule{dir: dir, pattern: fields[0], values: map[string]string{}}
+		for _, attr := range fields[1:] {
+			switch {
+			case strings.HasPrefix(attr, "-"):
+				rule.values[attr[1:]] = attributeUnset
+			case strings.HasPrefix(attr, "!"):
+				rule.values[attr[1:]] = ""
+			case strings.Contains(attr, "="):
+				name, value, _ := strings.Cut(attr, "=")
+				rule.values[name] = value
+			default:
+				rule.values[attr] = attributeSet
+			}
+		}
+		a.rules = append(a.rules, rule)
+	}
+
+	return a, s.Err()
+}
+
+// Merge adds the rules of other with a higher precedence than the existing
+// ones. Files must be merged from the root of the repository down.
+func (a *attributes) Merge(other *attributes) {
+	a.rules = append(a.rules, other.rules...)
+}
+
+// Lookup returns the value of attr for the given path, using the matching
+// rule with the highest precedence. ok is false if the attribute is
+// unspecified, which is always the case for a nil *attributes.
+func (a *attributes) Lookup(name, attr string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	for i := len(a.rules) - 1; i >= 0; i-- {
+		rule := a.rules[i]
+		value, ok := rule.values[attr]
+		if !ok || !rule.Matches(name) {
+			continue
+		}
+		return value, value != ""
+	}
+	return "", false
+}
+
+// IsSet returns whether attr is set, or set to "true", for the given path.
+func (a *attributes) IsSet(name, attr string) bool {
+	value, ok := a.Lookup(name, attr)
+	return ok && value == attributeSet
+}
+
+// AnySet returns the first of attrs that is set for the given path, if any.
+func (a *attributes) AnySet(name string, attrs []string) (string, bool) {
+	for _, attr := range attrs {
+		if a.IsSet(name, attr) {
+			return attr, true
+		}
+	}
+	return "", false
+}
+
+// Matches reports whether the rule's pattern matches the given path. Like
+// .gitignore, a pattern without a slash matches the file name at any depth
+// below the rule's directory, otherwise it is matched against the path
+// relative to that directory.
+func (r attributeRule) Matches(name string) bool {
+	if r.dir != "" {
+		if !strings.HasPrefix(name, r.dir+"/") {
+			return false
+		}
+		name = strings.TrimPrefix(name, r.dir+"/")
+	}
+
+	if !strings.Contains(r.pattern, "/") {
+		ok, _ := doublestar.Match(r.pattern, path.Base(name))
+		return ok
+	}
+	ok, _ := doublestar.Match(strings.TrimPrefix(r.pattern, "/"), name)
+	return ok
+}
+
+// attributesDirs returns every directory containing one of the given files,
+// including the root of the repository as an empty string, sorted so that
+// parent directories come before their subdirectories.
+func attributesDirs(filenames []string) []string {
+	seen := map[string]bool{"": true}
+	dirs := []string{""}
+	for _, filename := range filenames {
+		for dir := path.Dir(filename); dir != "." && dir != "/" && !seen[dir]; dir = path.Dir(dir) {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		di, dj := depth(dirs[i]), depth(dirs[j])
+		if di != dj {
+			return di < dj
+		}
+		return dirs[i] < dirs[j]
+	})
+	return dirs
+}
+
+func depth(dir string) int {
+	if dir == "" {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
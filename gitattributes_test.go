@@ -0,0 +1,199 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGitattributesFile = `
+# Comments and blank lines are ignored
+[attr]binary -diff -merge -text
+
+*.gen.go        linguist-generated=true
+vendor/**       linguist-vendored
+docs/*.md       linguist-documentation
+/fixtures/**    pr-size-ignore
+docs/README.md  -linguist-documentation
+*.txt           text eol=lf
+fixtures/keep/* !pr-size-ignore
+`
+
+func TestAttributesLookup(t *testing.T) {
+	t.Parallel()
+
+	attrs, err := parseAttributes("", []byte(testGitattributesFile))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		path          string
+		attr          string
+		expectedValue string
+		expectedOk    bool
+	}{
+		{
+			name:          "attribute with value",
+			path:          "pkg/api/client.gen.go",
+			attr:          "linguist-generated",
+			expectedValue: "true",
+			expectedOk:    true,
+		},
+		{
+			name:          "set attribute",
+			path:          "vendor/github.com/foo/bar.go",
+			attr:          "linguist-vendored",
+			expectedValue: "true",
+			expectedOk:    true,
+		},
+		{
+			name:          "later unset attribute overrides earlier set attribute",
+			path:          "docs/README.md",
+			attr:          "linguist-documentation",
+			expectedValue: "false",
+			expectedOk:    true,
+		},
+		{
+			name:          "later unspecified attribute overrides earlier set attribute",
+			path:          "fixtures/keep/input.json",
+			attr:          "pr-size-ignore",
+			expectedValue: "",
+			expectedOk:    false,
+		},
+		{
+			name:          "anchored pattern",
+			path:          "fixtures/big/input.json",
+			attr:          "pr-size-ignore",
+			expectedValue: "true",
+			expectedOk:    true,
+		},
+		{
+			name:          "anchored pattern doesn't match in subdirectories",
+			path:          "pkg/fixtures/input.json",
+			attr:          "pr-size-ignore",
+			expectedValue: "",
+			expectedOk:    false,
+		},
+		{
+			name:          "non-linguist attribute with value",
+			path:          "notes.txt",
+			attr:          "eol",
+			expectedValue: "lf",
+			expectedOk:    true,
+		},
+		{
+			name:          "attribute not specified for path",
+			path:          "main.go",
+			attr:          "linguist-generated",
+			expectedValue: "",
+			expectedOk:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			value, ok := attrs.Lookup(tt.path, tt.attr)
+			assert.Equal(t, tt.expectedValue, value)
+			assert.Equal(t, tt.expectedOk, ok)
+		})
+	}
+}
+
+func TestAttributesAnySet(t *testing.T) {
+	t.Parallel()
+
+	attrs, err := parseAttributes("", []byte(testGitattributesFile))
+	assert.NoError(t, err)
+
+	ignored := []string{"linguist-vendored", "linguist-documentation", "pr-size-ignore"}
+
+	attr, ok := attrs.AnySet("docs/guide.md", ignored)
+	assert.True(t, ok)
+	assert.Equal(t, "linguist-documentation", attr)
+
+	_, ok = attrs.AnySet("docs/README.md", ignored)
+	assert.False(t, ok)
+
+	_, ok = attrs.AnySet("main.go", ignored)
+	assert.False(t, ok)
+}
+
+func TestAttributesNestedPrecedence(t *testing.T) {
+	t.Parallel()
+
+	attrs := &attributes{}
+	for _, file := range []struct {
+		dir     string
+		content string
+	}{
+		{dir: "", content: "*.go linguist-generated\nclient/** linguist-generated\n"},
+		{dir: "services/api", content: "*.go -linguist-generated\nclient/*.go linguist-generated\n/root.txt linguist-generated\n"},
+	} {
+		parsed, err := parseAttributes(file.dir, []byte(file.content))
+		assert.NoError(t, err)
+		attrs.Merge(parsed)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "root rule applies outside nested directory",
+			path:     "pkg/main.go",
+			expected: true,
+		},
+		{
+			name:     "nested rule overrides root rule",
+			path:     "services/api/main.go",
+			expected: false,
+		},
+		{
+			name:     "nested rule patterns are relative to their directory",
+			path:     "services/api/client/client.go",
+			expected: true,
+		},
+		{
+			name:     "root rule patterns aren't relative to nested directories",
+			path:     "services/api/client.txt",
+			expected: false,
+		},
+		{
+			name:     "anchored nested rule matches relative to its directory",
+			path:     "services/api/root.txt",
+			expected: true,
+		},
+		{
+			name:     "anchored nested rule doesn't match at the root",
+			path:     "root.txt",
+			expected: false,
+		},
+		{
+			name:     "nested rules don't apply to sibling directories",
+			path:     "services/web/main.go",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, attrs.IsSet(tt.path, "linguist-generated"))
+		})
+	}
+}
+
+func TestAttributesDirs(t *testing.T) {
+	t.Parallel()
+
+	dirs := attributesDirs([]string{
+		"services/api/client/client.go",
+		"main.go",
+		"services/web/index.html",
+		"services/api/main.go",
+	})
+	assert.Equal(t, []string{"", "services", "services/api", "services/web", "services/api/client"}, dirs)
+}
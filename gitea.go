@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v50/github"
+)
+
+// defaultGiteaLabelColor is the color of labels that are added to a PR
+// without being configured, which GitHub creates with the same color.
+const defaultGiteaLabelColor = "ededed"
+
+// giteaProvider is the Provider for Gitea and Forgejo, whose REST API mostly
+// follows GitHub's, so its client is a GitHub client pointed at the Gitea API.
+// The calls that differ, such as those referring to labels by ID rather than
+// name, are translated. Gitea has no check runs, so check-run must be
+// disabled; commit-status works as on GitHub. A provider is for a single
+// repository, since label IDs are cached by name.
+type giteaProvider struct {
+	client *github.Client
+
+	mu       sync.Mutex
+	labelIDs map[string]int64
+}
+
+// newGiteaProvider returns a provider using the given client, which must have
+// been created by a function returned by newGiteaClientFunc.
+func newGiteaProvider(client *github.Client) *giteaProvider {
+	return &giteaProvider{client: client, labelIDs: make(map[string]int64)}
+}
+
+// newGiteaClientFunc returns a function creating GitHub clients for the Gitea
+// API at apiURL, e.g. https://gitea.example.com/api/v1, using the given HTTP
+// client.
+func newGiteaClientFunc(apiURL string) (func(*http.Client) *github.Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(apiURL, "/") + "/")
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid Gitea API URL: %q", apiURL)
+	}
+
+	return func(httpClient *http.Client) *github.Client {
+		client := github.NewClient(httpClient)
+		client.BaseURL = baseURL
+		return client
+	}, nil
+}
+
+// runningOnGitea returns whether the action is running on Gitea or Forgejo
+// Actions rather than GitHub Actions.
+func runningOnGitea(getenv func(string) string) bool {
+	return getenv("GITEA_ACTIONS") == "true" || getenv("FORGEJO_ACTIONS") == "true"
+}
+
+func (p *giteaProvider) Issues() IssuesClient {
+	return &giteaIssues{IssuesService: p.client.Issues, provider: p}
+}
+
+func (p *giteaProvider) PullRequests() PullRequestsClient {
+	return &giteaPullRequests{PullRequestsService: p.client.PullRequests, client: p.client}
+}
+
+func (p *giteaProvider) Repositories() RepositoriesClient {
+	return &giteaRepositories{RepositoriesService: p.client.Repositories}
+}
+
+// Git returns the GitHub client's, since Gitea lists trees like GitHub, though
+// a page at a time, so large trees are reported as truncated.
+func (p *giteaProvider) Git() GitClient {
+	return p.client.Git
+}
+
+func (p *giteaProvider) Users() UsersClient {
+	return p.client.Users
+}
+
+func (p *giteaProvider) Checks() ChecksClient {
+	return &giteaChecks{}
+}
+
+// rememberLabels records the IDs of labels by name.
+func (p *giteaProvider) rememberLabels(labels ...*github.Label) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, label := range labels {
+		p.labelIDs[label.GetName()] = label.GetID()
+	}
+}
+
+// labelID returns the ID of the label with the given name, listing the
+// repository's labels if it hasn't been seen yet, and whether it exists.
+func (p *giteaProvider) labelID(ctx context.Context, issues *giteaIssues, owner, repo, name string) (int64, bool, error) {
+	p.mu.Lock()
+	id, ok := p.labelIDs[name]
+	p.mu.Unlock()
+	if ok {
+		return id, true, nil
+	}
+
+	opts := &github.ListOptions{}
+	for {
+		// Listing labels remembers their IDs
+		_, resp, err := issues.ListLabels(ctx, owner, repo, opts)
+		if err != nil {
+			return 0, false, err
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id, ok = p.labelIDs[name]
+	return id, ok, nil
+}
+
+// giteaIssues is the IssuesClient of the Gitea provider. Comments follow the
+// GitHub API, but labels are referred to by ID and their colors are prefixed
+// with #.
+type giteaIssues struct {
+	*github.IssuesService
+	provider *giteaProvider
+}
+
+func (c *giteaIssues) ListLabels(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	labels, resp, err := c.IssuesService.ListLabels(ctx, owner, repo, opts)
+	if err != nil {
+		return nil, resp, err
+	}
+	for _, label := range labels {
+		label.Color = github.String(strings.TrimPrefix(label.GetColor(), "#"))
+	}
+	c.provider.rememberLabels(labels...)
+	return labels, resp, nil
+}
+
+func (c *giteaIssues) CreateLabel(ctx context.Context, owner, repo string, label *github.Label) (*github.Label, *github.Response, error) {
+	created, resp, err := c.IssuesService.CreateLabel(ctx, owner, repo, giteaLabel(label))
+	if err != nil {
+		return nil, resp, err
+	}
+	c.provider.rememberLabels(created)
+	return label, resp, nil
+}
+
+func (c *giteaIssues) EditLabel(ctx context.Context, owner, repo, name string, label *github.Label) (*github.Label, *github.Response, error) {
+	id, ok, err := c.provider.labelID(ctx, c, owner, repo, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("label %q doesn't exist", name)
+	}
+
+	req, err := c.provider.client.NewRequest(http.MethodPatch, fmt.Sprintf("repos/%v/%v/labels/%d", owner, repo, id), giteaLabel(label))
+	if err != nil {
+		return nil, nil, err
+	}
+	edited := &github.Label{}
+	resp, err := c.provider.client.Do(ctx, req, edited)
+	if err != nil {
+		return nil, resp, err
+	}
+	c.provider.rememberLabels(edited)
+	return label, resp, nil
+}
+
+func (c *giteaIssues) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error) {
+	ids := make([]int64, len(labels))
+	for i, name := range labels {
+		id, ok, err := c.provider.labelID(ctx, c, owner, repo, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		// GitHub creates labels that are added to an issue if they don't
+		// exist, but Gitea doesn't
+		if !ok {
+			label, _, err := c.IssuesService.CreateLabel(ctx, owner, repo, giteaLabel(&github.Label{Name: github.String(name), Color: github.String(defaultGiteaLabelColor)}))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create label %q: %w", name, err)
+			}
+			c.provider.rememberLabels(label)
+			id = label.GetID()
+		}
+		ids[i] = id
+	}
+
+	req, err := c.provider.client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%v/%v/issues/%d/labels", owner, repo, number), map[string][]int64{"labels": ids})
+	if err != nil {
+		return nil, nil, err
+	}
+	var added []*github.Label
+	resp, err := c.provider.client.Do(ctx, req, &added)
+	if err != nil {
+		return nil, resp, err
+	}
+	return added, resp, nil
+}
+
+func (c *giteaIssues) RemoveLabelForIssue(ctx context.Context, owner, repo string, number int, label string) (*github.Response, error) {
+	id, ok, err := c.provider.labelID(ctx, c, owner, repo, label)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("label %q doesn't exist", label)
+	}
+
+	req, err := c.provider.client.NewRequest(http.MethodDelete, fmt.Sprintf("repos/%v/%v/issues/%d/labels/%d", owner, repo, number, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.provider.client.Do(ctx, req, nil)
+}
+
+// ListIssueEvents lists the label changes in the issue's timeline as labeled
+// and unlabeled events, since Gitea has no issue events API.
+func (c *giteaIssues) ListIssueEvents(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.IssueEvent, *github.Response, error) {
+	query := url.Values{}
+	if opts != nil && opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts != nil && opts.PerPage > 0 {
+		query.Set("limit", strconv.Itoa(opts.PerPage))
+	}
+
+	req, err := c.provider.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%v/%v/issues/%d/timeline?%s", owner, repo, number, query.Encode()), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var timeline []struct {
+		Type string `json:"type"`
+		// Body is "1" when the label was added and empty when it was removed
+		Body  string        `json:"body"`
+		User  *github.User  `json:"user"`
+		Label *github.Label `json:"label"`
+	}
+	resp, err := c.provider.client.Do(ctx, req, &timeline)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	events := []*github.IssueEvent{}
+	for _, comment := range timeline {
+		if comment.Type != "label" || comment.Label == nil {
+			continue
+		}
+		event := "unlabeled"
+		if comment.Body == "1" {
+			event = "labeled"
+		}
+		events = append(events, &github.IssueEvent{Event: github.String(event), Actor: comment.User, Label: comment.Label})
+	}
+	return events, resp, nil
+}
+
+// giteaLabel returns a copy of label to send to Gitea, whose colors are
+// prefixed with #.
+func giteaLabel(label *github.Label) *github.Label {
+	return &github.Label{
+		Name:        label.Name,
+		Color:       github.String("#" + strings.TrimPrefix(label.GetColor(), "#")),
+		Description: label.Description,
+	}
+}
+
+// giteaPullRequests is the PullRequestsClient of the Gitea provider.
+type giteaPullRequests struct {
+	*github.PullRequestsService
+	client *github.Client
+}
+
+func (c *giteaPullRequests) List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	query := url.Values{}
+	if opts.State != "" {
+		query.Set("state", opts.State)
+	}
+	// Gitea only sorts by when PRs were updated in descending order
+	if opts.Sort == "updated" {
+		query.Set("sort", "recentupdate")
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PerPage > 0 {
+		query.Set("limit", strconv.Itoa(opts.PerPage))
+	}
+
+	req, err := c.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%v/%v/pulls?%s", owner, repo, query.Encode()), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var prs []*github.PullRequest
+	resp, err := c.client.Do(ctx, req, &prs)
+	if err != nil {
+		return nil, resp, err
+	}
+	return prs, resp, nil
+}
+
+// giteaRepositories is the RepositoriesClient of the Gitea provider.
+type giteaRepositories struct {
+	*github.RepositoriesService
+}
+
+func (c *giteaRepositories) GetPermissionLevel(ctx context.Context, owner, repo, user string) (*github.RepositoryPermissionLevel, *github.Response, error) {
+	level, resp, err := c.RepositoriesService.GetPermissionLevel(ctx, owner, repo, user)
+	if err != nil {
+		return nil, resp, err
+	}
+	// Gitea reports the owner's permission separately from admins'
+	if level.GetPermission() == "owner" {
+		level.Permission = github.String("admin")
+	}
+	return level, resp, nil
+}
+
+// giteaChecks is the ChecksClient of the Gitea provider, which has no check
+// runs.
+type giteaChecks struct{}
+
+func (c *giteaChecks) CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return nil, nil, errors.New("check runs aren't supported on Gitea, use commit-status instead")
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/assert"
+)
+
+// testGiteaAPI fakes the endpoints of the Gitea API that the labeler uses for
+// PR 1 of owner/repo, and records the requests that change it.
+type testGiteaAPI struct {
+	mu       sync.Mutex
+	labels   []*github.Label
+	prLabels []int64
+	requests []string
+}
+
+func (api *testGiteaAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, `{"message": "token is required"}`, http.StatusUnauthorized)
+		return
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/api/v1/repos/owner/repo/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodGet && path == "labels":
+		json.NewEncoder(w).Encode(api.labels)
+	case r.Method == http.MethodPost && path == "labels":
+		label := &github.Label{}
+		json.Unmarshal(body, label)
+		label.ID = ptr(int64(len(api.labels) + 1))
+		api.labels = append(api.labels, label)
+		api.requests = append(api.requests, fmt.Sprintf("create label %s %s", label.GetName(), label.GetColor()))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(label)
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "labels/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "labels/"), 10, 64)
+		label := api.labels[id-1]
+		json.Unmarshal(body, label)
+		api.requests = append(api.requests, fmt.Sprintf("edit label %d %s", id, label.GetColor()))
+		json.NewEncoder(w).Encode(label)
+	case r.Method == http.MethodGet && path == "pulls/1":
+		json.NewEncoder(w).Encode(api.pullRequest())
+	case r.Method == http.MethodGet && path == "pulls/1/files":
+		w.Write([]byte(`[{"filename": "main.go", "status": "modified", "additions": 150, "deletions": 10, "changes": 160}]`))
+	case r.Method == http.MethodPost && path == "issues/1/labels":
+		var add struct {
+			Labels []int64 `json:"labels"`
+		}
+		json.Unmarshal(body, &add)
+		api.prLabels = append(api.prLabels, add.Labels...)
+		api.requests = append(api.requests, fmt.Sprintf("add %v", add.Labels))
+		json.NewEncoder(w).Encode(api.pullRequest().Labels)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "issues/1/labels/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "issues/1/labels/"), 10, 64)
+		for i, labelID := range api.prLabels {
+			if labelID == id {
+				api.prLabels = append(api.prLabels[:i], api.prLabels[i+1:]...)
+				break
+			}
+		}
+		api.requests = append(api.requests, fmt.Sprintf("remove %d", id))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && path == "issues/1/comments":
+		w.Write([]byte(`[]`))
+	case r.Method == http.MethodGet && path == "issues/1/timeline":
+		w.Write([]byte(`[{"type": "comment", "body": "/size override S", "user": {"login": "someone"}}, {"type": "label", "body": "1", "user": {"login": "someone"}, "label": {"name": "size-pinned:size/S"}}, {"type": "label", "body": "", "user": {"login": "triager"}, "label": {"name": "size-pinned:size/S"}}]`))
+	case r.Method == http.MethodGet && path == "collaborators/someone/permission":
+		w.Write([]byte(`{"permission": "owner"}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (api *testGiteaAPI) pullRequest() *github.PullRequest {
+	labels := []*github.Label{}
+	for _, id := range api.prLabels {
+		labels = append(labels, api.labels[id-1])
+	}
+	return &github.PullRequest{
+		Number:       ptr(1),
+		State:        ptr("open"),
+		Labels:       labels,
+		ChangedFiles: ptr(1),
+		Head:         &github.PullRequestBranch{SHA: ptr(testHeadSHA)},
+		Base:         &github.PullRequestBranch{SHA: ptr(testBaseSHA), Repo: &github.Repository{Name: ptr("repo"), Owner: &github.User{Login: ptr("owner")}}},
+	}
+}
+
+func newTestGiteaProvider(t *testing.T, api *testGiteaAPI, token string) *giteaProvider {
+	t.Helper()
+
+	ts := httptest.NewServer(api)
+	t.Cleanup(ts.Close)
+	newClient, err := newGiteaClientFunc(ts.URL + "/api/v1")
+	assert.NoError(t, err)
+	return newGiteaProvider(newTokenClient(t.Context(), newClient, token))
+}
+
+func TestGiteaProvider(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		labels           []*github.Label
+		prLabels         []int64
+		expectedRequests []string
+	}{
+		{
+			name:             "creates and adds labels by ID",
+			expectedRequests: []string{"create label size/S #00ff00", "create label size/L #ff0000", "add [2]"},
+		},
+		{
+			name: "edits labels and replaces the previous size label by ID",
+			labels: []*github.Label{
+				{ID: ptr(int64(1)), Name: ptr("size/S"), Color: ptr("#00ff00"), Description: ptr("")},
+				{ID: ptr(int64(2)), Name: ptr("size/L"), Color: ptr("#000000"), Description: ptr("")},
+			},
+			prLabels:         []int64{1},
+			expectedRequests: []string{"edit label 2 #ff0000", "remove 1", "add [2]"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			api := &testGiteaAPI{labels: tc.labels, prLabels: tc.prLabels}
+			provider := newTestGiteaProvider(t, api, "token")
+			config := Config{Labels: []Label{
+				{Name: "size/S", MinLines: 0, Color: "00ff00"},
+				{Name: "size/L", MinLines: 100, Color: "ff0000"},
+			}}
+			action := githubactions.New(githubactions.WithWriter(io.Discard), githubactions.WithGetenv(func(string) string { return "" }))
+
+			labeler := newProviderLabeler(provider, action, config, "owner", "repo")
+			labeler.git = nil
+
+			pr, err := fetchPR(t.Context(), provider.PullRequests(), "owner", "repo", 1)
+			assert.NoError(t, err)
+
+			assert.NoError(t, labeler.CreateSizeLabels(t.Context()))
+			assert.NoError(t, labeler.ForEvent(FetchedPullRequest{pr: pr}).AddSizeLabel(t.Context()))
+			assert.Equal(t, tc.expectedRequests, api.requests)
+		})
+	}
+}
+
+func TestGiteaPermissionLevel(t *testing.T) {
+	t.Parallel()
+
+	provider := newTestGiteaProvider(t, &testGiteaAPI{}, "token")
+
+	level, _, err := provider.Repositories().GetPermissionLevel(t.Context(), "owner", "repo", "someone")
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", level.GetPermission())
+}
+
+func TestGiteaListIssueEvents(t *testing.T) {
+	t.Parallel()
+
+	provider := newTestGiteaProvider(t, &testGiteaAPI{}, "token")
+
+	events, _, err := provider.Issues().ListIssueEvents(t.Context(), "owner", "repo", 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*github.IssueEvent{
+		{Event: ptr("labeled"), Actor: &github.User{Login: ptr("someone")}, Label: &github.Label{Name: ptr("size-pinned:size/S")}},
+		{Event: ptr("unlabeled"), Actor: &github.User{Login: ptr("triager")}, Label: &github.Label{Name: ptr("size-pinned:size/S")}},
+	}, events)
+}
+
+func TestGiteaErrors(t *testing.T) {
+	t.Parallel()
+
+	provider := newTestGiteaProvider(t, &testGiteaAPI{}, "wrong-token")
+
+	_, _, err := provider.Issues().ListLabels(t.Context(), "owner", "repo", nil)
+	var errResp *github.ErrorResponse
+	assert.ErrorAs(t, err, &errResp)
+	assert.Equal(t, http.StatusUnauthorized, errResp.Response.StatusCode)
+
+	_, _, err = provider.Checks().CreateCheckRun(t.Context(), "owner", "repo", github.CreateCheckRunOptions{})
+	assert.ErrorContains(t, err, "check runs aren't supported on Gitea")
+
+	_, err = newGiteaClientFunc("gitea.example.com")
+	assert.ErrorContains(t, err, "invalid Gitea API URL")
+}
+
+func TestRunningOnGitea(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected bool
+	}{
+		{name: "GitHub Actions", env: map[string]string{"GITHUB_ACTIONS": "true"}, expected: false},
+		{name: "Gitea Actions", env: map[string]string{"GITHUB_ACTIONS": "true", "GITEA_ACTIONS": "true"}, expected: true},
+		{name: "Forgejo Actions", env: map[string]string{"GITHUB_ACTIONS": "true", "FORGEJO_ACTIONS": "true"}, expected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, runningOnGitea(func(key string) string { return tc.env[key] }))
+		})
+	}
+}
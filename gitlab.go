@@ -0,0 +1,781 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// gitlabProvider is the GitLab Provider, which labels the merge requests of a
+// single project. It translates the GitHub API calls the labeler makes to the
+// GitLab REST API, so that merge requests are sized and labeled exactly like
+// PRs. GitLab has no check runs, so check-run must be disabled; commit-status
+// works as on GitHub.
+type gitlabProvider struct {
+	client *gitlabClient
+}
+
+// newGitLabProvider returns a provider for the given project, which is its ID
+// or path, on the GitLab instance whose REST API is at apiURL, e.g.
+// https://gitlab.com/api/v4. owner and repo are the namespace and name of the
+// project, which are reported as the repository of its merge requests.
+func newGitLabProvider(httpClient *http.Client, apiURL, token, project, owner, repo string) *gitlabProvider {
+	return &gitlabProvider{client: &gitlabClient{
+		httpClient: httpClient,
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		token:      token,
+		project:    project,
+		owner:      owner,
+		repo:       repo,
+		noteMRs:    make(map[int64]int),
+	}}
+}
+
+func (p *gitlabProvider) Issues() IssuesClient {
+	return &gitlabIssues{p.client}
+}
+
+func (p *gitlabProvider) PullRequests() PullRequestsClient {
+	return &gitlabMergeRequests{p.client}
+}
+
+func (p *gitlabProvider) Repositories() RepositoriesClient {
+	return &gitlabRepositories{p.client}
+}
+
+func (p *gitlabProvider) Git() GitClient {
+	return &gitlabGit{p.client}
+}
+
+func (p *gitlabProvider) Users() UsersClient {
+	return &gitlabUsers{p.client}
+}
+
+func (p *gitlabProvider) Checks() ChecksClient {
+	return &gitlabChecks{}
+}
+
+// gitlabClient calls the GitLab REST API on behalf of a project.
+type gitlabClient struct {
+	httpClient *http.Client
+	apiURL     string
+	token      string
+	project    string
+	owner      string
+	repo       string
+
+	// noteMRs maps the ID of each note seen to the merge request it's on,
+	// since notes can only be edited or deleted through their merge request
+	mu      sync.Mutex
+	noteMRs map[int64]int
+}
+
+// gitlabLabel is a project label in the GitLab API.
+type gitlabLabel struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+// gitlabNote is a comment on a merge request in the GitLab API.
+type gitlabNote struct {
+	ID     int64      `json:"id"`
+	Body   string     `json:"body"`
+	System bool       `json:"system"`
+	Author gitlabUser `json:"author"`
+}
+
+// gitlabUser is a user in the GitLab API.
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+// user converts a GitLab user to a GitHub user. GitLab's bot users, such as
+// those of project access tokens, are reported as users, since they're
+// project members like any other.
+func (u gitlabUser) user() *github.User {
+	return &github.User{Login: github.String(u.Username), Type: github.String("User")}
+}
+
+// gitlabMergeRequest is a merge request in the GitLab API.
+type gitlabMergeRequest struct {
+	IID         int      `json:"iid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	State       string   `json:"state"`
+	Labels      []string `json:"labels"`
+	SHA         string   `json:"sha"`
+	// ChangesCount is the number of files changed, or e.g. "1000+" if there
+	// are more than GitLab lists.
+	ChangesCount string     `json:"changes_count"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	ClosedAt     *time.Time `json:"closed_at"`
+	MergedAt     *time.Time `json:"merged_at"`
+	DiffRefs     struct {
+		BaseSHA string `json:"base_sha"`
+	} `json:"diff_refs"`
+}
+
+// gitlabDiff is the diff of a file in the GitLab API.
+type gitlabDiff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	Diff        string `json:"diff"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+	// Collapsed and TooLarge are set when the file's diff is too large for
+	// GitLab to include.
+	Collapsed bool `json:"collapsed"`
+	TooLarge  bool `json:"too_large"`
+}
+
+// do sends a request to the GitLab API and decodes the JSON response into v,
+// if it's not nil. Errors are returned as *github.ErrorResponse, and the next
+// page as the response's NextPage, so that the labeler handles them like
+// GitHub's.
+func (c *gitlabClient) do(ctx context.Context, method, path string, query url.Values, body, v any) (*github.Response, error) {
+	u := c.apiURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	ghResp := &github.Response{Response: resp}
+	ghResp.NextPage, _ = strconv.Atoi(resp.Header.Get("X-Next-Page"))
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		message, _ := io.ReadAll(resp.Body)
+		return ghResp, &github.ErrorResponse{Response: resp, Message: strings.TrimSpace(string(message))}
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return ghResp, fmt.Errorf("failed to decode GitLab API response: %w", err)
+		}
+	}
+	return ghResp, nil
+}
+
+// projectPath returns the path of an endpoint of the project, whose elements
+// are escaped.
+func (c *gitlabClient) projectPath(elems ...string) string {
+	path := "/projects/" + url.PathEscape(c.project)
+	for _, elem := range elems {
+		path += "/" + url.PathEscape(elem)
+	}
+	return path
+}
+
+// pageQuery returns the query parameters requesting the given page.
+func pageQuery(opts *github.ListOptions) url.Values {
+	query := url.Values{}
+	if opts == nil {
+		return query
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	return query
+}
+
+// pullRequest converts a merge request to a GitHub pull request. If GitLab
+// caps its number of changed files, e.g. at "1000+", it's reported as one more
+// than the cap so that the labeler knows the files listed are incomplete.
+func (c *gitlabClient) pullRequest(mr gitlabMergeRequest) *github.PullRequest {
+	labels := make([]*github.Label, len(mr.Labels))
+	for i, name := range mr.Labels {
+		labels[i] = &github.Label{Name: github.String(name)}
+	}
+
+	state := "closed"
+	if mr.State == "opened" {
+		state = "open"
+	}
+	closedAt := mr.ClosedAt
+	if mr.MergedAt != nil {
+		closedAt = mr.MergedAt
+	}
+
+	pr := &github.PullRequest{
+		Number: github.Int(mr.IID),
+		Title:  github.String(mr.Title),
+		Body:   github.String(mr.Description),
+		State:  github.String(state),
+		Labels: labels,
+		Head:   &github.PullRequestBranch{SHA: github.String(mr.SHA)},
+		Base: &github.PullRequestBranch{
+			SHA: github.String(mr.DiffRefs.BaseSHA),
+			Repo: &github.Repository{
+				Name:  github.String(c.repo),
+				Owner: &github.User{Login: github.String(c.owner)},
+			},
+		},
+	}
+	if count, capped := strings.CutSuffix(mr.ChangesCount, "+"); count != "" {
+		if n, err := strconv.Atoi(count); err == nil {
+			if capped {
+				n++
+			}
+			pr.ChangedFiles = github.Int(n)
+		}
+	}
+	if mr.UpdatedAt != nil {
+		pr.UpdatedAt = &github.Timestamp{Time: *mr.UpdatedAt}
+	}
+	if closedAt != nil {
+		pr.ClosedAt = &github.Timestamp{Time: *closedAt}
+	}
+	return pr
+}
+
+// commitFile converts the diff of a file to a GitHub commit file, counting
+// the lines it adds and deletes since GitLab doesn't.
+func commitFile(diff gitlabDiff) *github.CommitFile {
+	status := "modified"
+	switch {
+	case diff.NewFile:
+		status = "added"
+	case diff.DeletedFile:
+		status = "removed"
+	case diff.RenamedFile:
+		status = "renamed"
+	}
+
+	file := &github.CommitFile{
+		Filename: github.String(diff.NewPath),
+		Status:   github.String(status),
+	}
+	if diff.RenamedFile {
+		file.PreviousFilename = github.String(diff.OldPath)
+	}
+	if diff.Diff != "" {
+		file.Patch = github.String(diff.Diff)
+	}
+
+	additions, deletions := 0, 0
+	for _, line := range strings.Split(diff.Diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			additions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+	file.Additions = github.Int(additions)
+	file.Deletions = github.Int(deletions)
+	file.Changes = github.Int(additions + deletions)
+	return file
+}
+
+// gitlabIssues is the IssuesClient of the GitLab provider. Labels are project
+// labels and comments are merge request notes.
+type gitlabIssues struct {
+	*gitlabClient
+}
+
+func (c *gitlabIssues) ListLabels(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	var labels []gitlabLabel
+	resp, err := c.do(ctx, http.MethodGet, c.projectPath("labels"), pageQuery(opts), nil, &labels)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	ghLabels := make([]*github.Label, len(labels))
+	for i, label := range labels {
+		ghLabels[i] = &github.Label{
+			Name:        github.String(label.Name),
+			Color:       github.String(strings.TrimPrefix(label.Color, "#")),
+			Description: github.String(label.Description),
+		}
+	}
+	return ghLabels, resp, nil
+}
+
+func (c *gitlabIssues) CreateLabel(ctx context.Context, owner, repo string, label *github.Label) (*github.Label, *github.Response, error) {
+	resp, err := c.do(ctx, http.MethodPost, c.projectPath("labels"), nil, gitlabLabel{
+		Name:        label.GetName(),
+		Color:       "#" + label.GetColor(),
+		Description: label.GetDescription(),
+	}, nil)
+	return label, resp, err
+}
+
+func (c *gitlabIssues) EditLabel(ctx context.Context, owner, repo, name string, label *github.Label) (*github.Label, *github.Response, error) {
+	body := map[string]string{
+		"color":       "#" + label.GetColor(),
+		"description": label.GetDescription(),
+	}
+	if label.GetName() != name {
+		body["new_name"] = label.GetName()
+	}
+	resp, err := c.do(ctx, http.MethodPut, c.projectPath("labels", name), nil, body, nil)
+	return label, resp, err
+}
+
+func (c *gitlabIssues) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error) {
+	var mr gitlabMergeRequest
+	resp, err := c.do(ctx, http.MethodPut, c.projectPath("merge_requests", strconv.Itoa(number)), nil,
+		map[string]string{"add_labels": strings.Join(labels, ",")}, &mr)
+	if err != nil {
+		return nil, resp, err
+	}
+	return c.pullRequest(mr).Labels, resp, nil
+}
+
+func (c *gitlabIssues) RemoveLabelForIssue(ctx context.Context, owner, repo string, number int, label string) (*github.Response, error) {
+	return c.do(ctx, http.MethodPut, c.projectPath("merge_requests", strconv.Itoa(number)), nil,
+		map[string]string{"remove_labels": label}, nil)
+}
+
+func (c *gitlabIssues) ListComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	var listOpts *github.ListOptions
+	if opts != nil {
+		listOpts = &opts.ListOptions
+	}
+	query := pageQuery(listOpts)
+	query.Set("sort", "asc")
+	query.Set("order_by", "created_at")
+
+	var notes []gitlabNote
+	resp, err := c.do(ctx, http.MethodGet, c.projectPath("merge_requests", strconv.Itoa(number), "notes"), query, nil, &notes)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	comments := []*github.IssueComment{}
+	for _, note := range notes {
+		// System notes record activity like label changes, they aren't comments
+		if note.System {
+			continue
+		}
+		c.rememberNote(note.ID, number)
+		comments = append(comments, &github.IssueComment{ID: github.Int64(note.ID), Body: github.String(note.Body), User: note.Author.user()})
+	}
+	return comments, resp, nil
+}
+
+func (c *gitlabIssues) CreateComment(ctx context.Context, owner, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	var note gitlabNote
+	resp, err := c.do(ctx, http.MethodPost, c.projectPath("merge_requests", strconv.Itoa(number), "notes"), nil,
+		map[string]string{"body": comment.GetBody()}, &note)
+	if err != nil {
+		return nil, resp, err
+	}
+	c.rememberNote(note.ID, number)
+	return &github.IssueComment{ID: github.Int64(note.ID), Body: github.String(note.Body)}, resp, nil
+}
+
+func (c *gitlabIssues) EditComment(ctx context.Context, owner, repo string, commentID int64, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	number, err := c.noteMR(commentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var note gitlabNote
+	resp, err := c.do(ctx, http.MethodPut, c.projectPath("merge_requests", strconv.Itoa(number), "notes", strconv.FormatInt(commentID, 10)), nil,
+		map[string]string{"body": comment.GetBody()}, &note)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &github.IssueComment{ID: github.Int64(note.ID), Body: github.String(note.Body)}, resp, nil
+}
+
+func (c *gitlabIssues) DeleteComment(ctx context.Context, owner, repo string, commentID int64) (*github.Response, error) {
+	number, err := c.noteMR(commentID)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodDelete, c.projectPath("merge_requests", strconv.Itoa(number), "notes", strconv.FormatInt(commentID, 10)), nil, nil, nil)
+}
+
+// ListIssueEvents lists the label events of the merge request as labeled and
+// unlabeled events.
+func (c *gitlabIssues) ListIssueEvents(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.IssueEvent, *github.Response, error) {
+	var labelEvents []struct {
+		User  gitlabUser   `json:"user"`
+		Label *gitlabLabel `json:"label"`
+		// Action is add or remove
+		Action string `json:"action"`
+	}
+	resp, err := c.do(ctx, http.MethodGet, c.projectPath("merge_requests", strconv.Itoa(number), "resource_label_events"), pageQuery(opts), nil, &labelEvents)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	events := []*github.IssueEvent{}
+	for _, labelEvent := range labelEvents {
+		// The label of an event is null once it's deleted
+		if labelEvent.Label == nil {
+			continue
+		}
+		event := "labeled"
+		if labelEvent.Action == "remove" {
+			event = "unlabeled"
+		}
+		events = append(events, &github.IssueEvent{
+			Event: github.String(event),
+			Actor: labelEvent.User.user(),
+			Label: &github.Label{Name: github.String(labelEvent.Label.Name)},
+		})
+	}
+	return events, resp, nil
+}
+
+// rememberNote records which merge request a note is on.
+func (c *gitlabClient) rememberNote(id int64, number int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.noteMRs[id] = number
+}
+
+// noteMR returns the merge request a note is on, which is known once the note
+// has been listed or created.
+func (c *gitlabClient) noteMR(id int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	number, ok := c.noteMRs[id]
+	if !ok {
+		return 0, fmt.Errorf("note %d hasn't been listed, so its merge request is unknown", id)
+	}
+	return number, nil
+}
+
+// gitlabMergeRequests is the PullRequestsClient of the GitLab provider.
+type gitlabMergeRequests struct {
+	*gitlabClient
+}
+
+func (c *gitlabMergeRequests) ListFiles(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+	var diffs []gitlabDiff
+	resp, err := c.do(ctx, http.MethodGet, c.projectPath("merge_requests", strconv.Itoa(number), "diffs"), pageQuery(opts), nil, &diffs)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		// GitLab before 15.7 only lists all the changes at once
+		var changes struct {
+			Changes []gitlabDiff `json:"changes"`
+		}
+		resp, err = c.do(ctx, http.MethodGet, c.projectPath("merge_requests", strconv.Itoa(number), "changes"), nil, nil, &changes)
+		diffs = changes.Changes
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+
+	files := []*github.CommitFile{}
+	for _, diff := range diffs {
+		// The lines changed in files whose diffs are too large to include
+		// can't be counted, so they're left out like the files GitLab doesn't
+		// list at all. With fewer files listed than changed, the labeler falls
+		// back to a local git diff.
+		if (diff.Collapsed || diff.TooLarge) && diff.Diff == "" {
+			continue
+		}
+		files = append(files, commitFile(diff))
+	}
+	return files, resp, nil
+}
+
+func (c *gitlabMergeRequests) List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	query := pageQuery(&opts.ListOptions)
+	// GitHub's closed PRs include merged ones, which GitLab lists separately
+	switch opts.State {
+	case "open":
+		query.Set("state", "opened")
+	default:
+		query.Set("state", "all")
+	}
+	if opts.Sort == "updated" {
+		query.Set("order_by", "updated_at")
+	}
+	if opts.Direction != "" {
+		query.Set("sort", opts.Direction)
+	}
+
+	var mrs []gitlabMergeRequest
+	resp, err := c.do(ctx, http.MethodGet, c.projectPath("merge_requests"), query, nil, &mrs)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	prs := []*github.PullRequest{}
+	for _, mr := range mrs {
+		pr := c.pullRequest(mr)
+		if opts.State == "closed" && pr.GetState() != "closed" {
+			continue
+		}
+		prs = append(prs, pr)
+	}
+	return prs, resp, nil
+}
+
+func (c *gitlabMergeRequests) Get(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error) {
+	var mr gitlabMergeRequest
+	resp, err := c.do(ctx, http.MethodGet, c.projectPath("merge_requests", strconv.Itoa(number)), nil, nil, &mr)
+	if err != nil {
+		return nil, resp, err
+	}
+	return c.pullRequest(mr), resp, nil
+}
+
+// gitlabRepositories is the RepositoriesClient of the GitLab provider.
+type gitlabRepositories struct {
+	*gitlabClient
+}
+
+func (c *gitlabRepositories) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	query := url.Values{}
+	if opts != nil && opts.Ref != "" {
+		query.Set("ref", opts.Ref)
+	}
+
+	var file struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	resp, err := c.do(ctx, http.MethodGet, c.projectPath("repository", "files", strings.TrimPrefix(path, "/")), query, nil, &file)
+	if err != nil {
+		return nil, nil, resp, err
+	}
+	return &github.RepositoryContent{
+		Path:     github.String(path),
+		Content:  github.String(file.Content),
+		Encoding: github.String(file.Encoding),
+	}, nil, resp, nil
+}
+
+func (c *gitlabRepositories) CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error) {
+	state := status.GetState()
+	if state == "failure" || state == "error" {
+		state = "failed"
+	}
+	resp, err := c.do(ctx, http.MethodPost, c.projectPath("statuses", ref), nil, map[string]string{
+		"state":       state,
+		"name":        status.GetContext(),
+		"description": status.GetDescription(),
+		"target_url":  status.GetTargetURL(),
+	}, nil)
+	return status, resp, err
+}
+
+func (c *gitlabRepositories) GetPermissionLevel(ctx context.Context, owner, repo, user string) (*github.RepositoryPermissionLevel, *github.Response, error) {
+	var users []struct {
+		ID int64 `json:"id"`
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/users", url.Values{"username": {user}}, nil, &users)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(users) == 0 {
+		return &github.RepositoryPermissionLevel{Permission: github.String("none")}, resp, nil
+	}
+
+	var member struct {
+		AccessLevel int `json:"access_level"`
+	}
+	resp, err = c.do(ctx, http.MethodGet, c.projectPath("members", "all", strconv.FormatInt(users[0].ID, 10)), nil, nil, &member)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return &github.RepositoryPermissionLevel{Permission: github.String("none")}, resp, nil
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+
+	// Developers can push, so they're the equivalent of GitHub's write access
+	permission := "none"
+	switch {
+	case member.AccessLevel >= 40:
+		permission = "admin"
+	case member.AccessLevel >= 30:
+		permission = "write"
+	case member.AccessLevel >= 10:
+		permission = "read"
+	}
+	return &github.RepositoryPermissionLevel{Permission: github.String(permission)}, resp, nil
+}
+
+// gitlabUsers is the UsersClient of the GitLab provider.
+type gitlabUsers struct {
+	*gitlabClient
+}
+
+// Get returns the given user, or the authenticated user if user is empty.
+func (c *gitlabUsers) Get(ctx context.Context, user string) (*github.User, *github.Response, error) {
+	if user == "" {
+		var authenticated gitlabUser
+		resp, err := c.do(ctx, http.MethodGet, "/user", nil, nil, &authenticated)
+		if err != nil {
+			return nil, resp, err
+		}
+		return authenticated.user(), resp, nil
+	}
+
+	var users []gitlabUser
+	resp, err := c.do(ctx, http.MethodGet, "/users", url.Values{"username": {user}}, nil, &users)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(users) == 0 {
+		return nil, resp, fmt.Errorf("user %q doesn't exist", user)
+	}
+	return users[0].user(), resp, nil
+}
+
+// gitlabMaxTreePages is the most pages of a repository tree that are listed
+// before it's reported as truncated, since GitLab lists at most 100 entries
+// a page.
+const gitlabMaxTreePages = 20
+
+// gitlabGit is the GitClient of the GitLab provider.
+type gitlabGit struct {
+	*gitlabClient
+}
+
+func (c *gitlabGit) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error) {
+	query := url.Values{"ref": {sha}, "per_page": {"100"}}
+	if recursive {
+		query.Set("recursive", "true")
+	}
+
+	tree := &github.Tree{SHA: github.String(sha), Truncated: github.Bool(false)}
+	for page := 1; ; page++ {
+		if page > gitlabMaxTreePages {
+			tree.Truncated = github.Bool(true)
+			return tree, nil, nil
+		}
+		query.Set("page", strconv.Itoa(page))
+
+		var entries []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		}
+		resp, err := c.do(ctx, http.MethodGet, c.projectPath("repository", "tree"), query, nil, &entries)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, entry := range entries {
+			tree.Entries = append(tree.Entries, &github.TreeEntry{Path: github.String(entry.Path), Type: github.String(entry.Type)})
+		}
+		if resp.NextPage == 0 {
+			return tree, resp, nil
+		}
+	}
+}
+
+// gitlabChecks is the ChecksClient of the GitLab provider, which has no check
+// runs.
+type gitlabChecks struct{}
+
+func (c *gitlabChecks) CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return nil, nil, errors.New("check runs aren't supported on GitLab, use commit-status instead")
+}
+
+// runGitLab implements the gitlab command, which labels the merge request of a
+// GitLab CI merge request pipeline like the action labels PRs. The project,
+// merge request and API URL are read from GitLab CI's predefined variables,
+// and the token from GITLAB_TOKEN, which needs the api scope since job tokens
+// can't manage labels.
+func runGitLab(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gitlab", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", ".github/pr-size-labeler.yml", "path to the pr-size-labeler config file")
+	dryRun := flags.Bool("dry-run", false, "log the changes that would be made instead of making them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	iid := os.Getenv("CI_MERGE_REQUEST_IID")
+	if iid == "" {
+		fmt.Fprintf(stderr, "CI_MERGE_REQUEST_IID isn't set, this isn't a merge request pipeline, skipping\n")
+		return 0
+	}
+	number, err := strconv.Atoi(iid)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid CI_MERGE_REQUEST_IID: %q\n", iid)
+		return 1
+	}
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		fmt.Fprintf(stderr, "missing required environment variable: GITLAB_TOKEN\n")
+		return 1
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	action := githubactions.New(
+		githubactions.WithWriter(stdout),
+		// There is no job summary or outputs outside of GitHub Actions
+		githubactions.WithGetenv(func(string) string { return "" }),
+	)
+	owner, repo := os.Getenv("CI_PROJECT_NAMESPACE"), os.Getenv("CI_PROJECT_NAME")
+	provider := newGitLabProvider(http.DefaultClient, os.Getenv("CI_API_V4_URL"), token, os.Getenv("CI_PROJECT_ID"), owner, repo)
+
+	labeler := newProviderLabeler(provider, action, config, owner, repo)
+	// GitLab leaves out the diffs of large files and merge requests, which are
+	// then sized with a git diff of the project's checkout
+	labeler.git = &gitRepo{dir: os.Getenv("CI_PROJECT_DIR")}
+	// The local .gitattributes fallback reads the working directory, which is
+	// only the project's checkout if the job hasn't changed directory
+	if wd, err := os.Getwd(); err != nil || wd != os.Getenv("CI_PROJECT_DIR") {
+		labeler.config.LocalGitattributesFallback = false
+	}
+	if *dryRun {
+		labeler.DryRun()
+	}
+
+	pr, err := fetchPR(ctx, provider.PullRequests(), owner, repo, number)
+	if err == nil {
+		err = labeler.CreateSizeLabels(ctx)
+	}
+	if err == nil {
+		err = labeler.ForEvent(FetchedPullRequest{pr: pr}).AddSizeLabel(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/sethvargo/go-githubactions"
+	"github.com/stretchr/testify/assert"
+)
+
+// testGitLabAPI fakes the endpoints of the GitLab API that the labeler uses
+// for merge request 1 of project 7, and records the requests that change it.
+type testGitLabAPI struct {
+	// noDiffs makes the diffs endpoint 404 like GitLab before 15.7.
+	noDiffs bool
+
+	mu       sync.Mutex
+	labels   []gitlabLabel
+	mrLabels []string
+	requests []string
+}
+
+func (api *testGitLabAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "token" {
+		http.Error(w, `{"message": "401 Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	body := map[string]string{}
+	if r.Body != nil {
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+	}
+
+	diffs := []gitlabDiff{
+		{OldPath: "main.go", NewPath: "main.go", Diff: "@@ -1,2 +1,3 @@\n-a\n+b\n+c\n d\n"},
+		{OldPath: "new.go", NewPath: "new.go", NewFile: true, Diff: "@@ -0,0 +1,1 @@\n+e\n"},
+		{OldPath: "generated.go", NewPath: "generated.go", TooLarge: true},
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/7/labels":
+		json.NewEncoder(w).Encode(api.labels)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v4/projects/7/labels":
+		api.requests = append(api.requests, "create label "+body["name"]+" "+body["color"])
+		api.labels = append(api.labels, gitlabLabel{Name: body["name"], Color: body["color"], Description: body["description"]})
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/7/merge_requests/1":
+		json.NewEncoder(w).Encode(api.mergeRequest())
+	case r.Method == http.MethodPut && r.URL.Path == "/api/v4/projects/7/merge_requests/1":
+		if body["add_labels"] != "" {
+			api.requests = append(api.requests, "add "+body["add_labels"])
+			api.mrLabels = append(api.mrLabels, body["add_labels"])
+		}
+		if body["remove_labels"] != "" {
+			api.requests = append(api.requests, "remove "+body["remove_labels"])
+			for i, label := range api.mrLabels {
+				if label == body["remove_labels"] {
+					api.mrLabels = append(api.mrLabels[:i], api.mrLabels[i+1:]...)
+					break
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(api.mergeRequest())
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/7/merge_requests/1/diffs":
+		if api.noDiffs {
+			http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("page") == "2" {
+			json.NewEncoder(w).Encode(diffs[1:])
+			return
+		}
+		w.Header().Set("X-Next-Page", "2")
+		json.NewEncoder(w).Encode(diffs[:1])
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/7/merge_requests/1/changes":
+		json.NewEncoder(w).Encode(map[string]any{"changes": diffs})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/user":
+		w.Write([]byte(`{"id": 3, "username": "project_7_bot"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/7/repository/tree":
+		if r.URL.Query().Get("ref") != testBaseSHA || r.URL.Query().Get("recursive") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"path": "services/.gitattributes", "type": "blob"}]`))
+			return
+		}
+		w.Header().Set("X-Next-Page", "2")
+		w.Write([]byte(`[{"path": ".gitattributes", "type": "blob"}, {"path": "services", "type": "tree"}]`))
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/7/merge_requests/1/resource_label_events":
+		w.Write([]byte(`[{"user": {"username": "maintainer"}, "label": {"name": "size-pinned:size::S"}, "action": "add"}, {"user": {"username": "triager"}, "label": null, "action": "add"}, {"user": {"username": "triager"}, "label": {"name": "size-pinned:size::S"}, "action": "remove"}]`))
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/7/merge_requests/1/notes":
+		json.NewEncoder(w).Encode([]gitlabNote{
+			{ID: 1, Body: "added ~size::S label", System: true},
+			{ID: 2, Body: "LGTM", Author: gitlabUser{Username: "octocat"}},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (api *testGitLabAPI) mergeRequest() gitlabMergeRequest {
+	mr := gitlabMergeRequest{IID: 1, State: "opened", Labels: append([]string{}, api.mrLabels...), SHA: testHeadSHA, ChangesCount: "3"}
+	mr.DiffRefs.BaseSHA = testBaseSHA
+	return mr
+}
+
+func newTestGitLabProvider(t *testing.T, api *testGitLabAPI) *gitlabProvider {
+	t.Helper()
+
+	ts := httptest.NewServer(api)
+	t.Cleanup(ts.Close)
+	return newGitLabProvider(ts.Client(), ts.URL+"/api/v4/", "token", "7", "group", "project")
+}
+
+func TestGitLabProvider(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		noDiffs          bool
+		mrLabels         []string
+		expectedRequests []string
+	}{
+		{
+			name:             "labels merge requests with scoped labels",
+			expectedRequests: []string{"create label size::S #00ff00", "create label size::L #ff0000", "add size::S"},
+		},
+		{
+			name:             "replaces the previous size label",
+			mrLabels:         []string{"size::L", "bug"},
+			expectedRequests: []string{"create label size::S #00ff00", "create label size::L #ff0000", "remove size::L", "add size::S"},
+		},
+		{
+			name:             "lists changes on older GitLab versions",
+			noDiffs:          true,
+			expectedRequests: []string{"create label size::S #00ff00", "create label size::L #ff0000", "add size::S"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			api := &testGitLabAPI{noDiffs: tc.noDiffs, mrLabels: tc.mrLabels}
+			provider := newTestGitLabProvider(t, api)
+			config := Config{Labels: []Label{
+				{Name: "size::S", MinLines: 0, Color: "00ff00"},
+				{Name: "size::L", MinLines: 100, Color: "ff0000"},
+			}}
+			action := githubactions.New(githubactions.WithWriter(io.Discard), githubactions.WithGetenv(func(string) string { return "" }))
+
+			labeler := newProviderLabeler(provider, action, config, "group", "project")
+			labeler.git = nil
+
+			pr, err := fetchPR(t.Context(), provider.PullRequests(), "group", "project", 1)
+			assert.NoError(t, err)
+			assert.Equal(t, testBaseSHA, pr.GetBase().GetSHA())
+
+			assert.NoError(t, labeler.CreateSizeLabels(t.Context()))
+			assert.NoError(t, labeler.ForEvent(FetchedPullRequest{pr: pr}).AddSizeLabel(t.Context()))
+			assert.Equal(t, tc.expectedRequests, api.requests)
+		})
+	}
+}
+
+func TestGitLabListFiles(t *testing.T) {
+	t.Parallel()
+
+	provider := newTestGitLabProvider(t, &testGitLabAPI{})
+
+	files, resp, err := provider.PullRequests().ListFiles(t.Context(), "group", "project", 1, &github.ListOptions{PerPage: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resp.NextPage)
+	assert.Equal(t, "main.go", files[0].GetFilename())
+	assert.Equal(t, "modified", files[0].GetStatus())
+	assert.Equal(t, 2, files[0].GetAdditions())
+	assert.Equal(t, 1, files[0].GetDeletions())
+
+	files, resp, err = provider.PullRequests().ListFiles(t.Context(), "group", "project", 1, &github.ListOptions{Page: resp.NextPage, PerPage: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, resp.NextPage)
+	// Files whose diffs are too large are left out
+	assert.Len(t, files, 1)
+	assert.Equal(t, "added", files[0].GetStatus())
+	assert.Equal(t, 1, files[0].GetAdditions())
+}
+
+func TestGitLabChangedFiles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		changesCount string
+		expected     *int
+	}{
+		{changesCount: "3", expected: ptr(3)},
+		{changesCount: "1000+", expected: ptr(1001)},
+		{changesCount: "", expected: nil},
+	}
+
+	client := &gitlabClient{owner: "group", repo: "project"}
+	for _, tc := range tests {
+		t.Run(tc.changesCount, func(t *testing.T) {
+			t.Parallel()
+
+			pr := client.pullRequest(gitlabMergeRequest{IID: 1, ChangesCount: tc.changesCount})
+			assert.Equal(t, tc.expected, pr.ChangedFiles)
+		})
+	}
+}
+
+func TestGitLabGetTree(t *testing.T) {
+	t.Parallel()
+
+	provider := newTestGitLabProvider(t, &testGitLabAPI{})
+
+	tree, _, err := provider.Git().GetTree(t.Context(), "group", "project", testBaseSHA, true)
+	assert.NoError(t, err)
+	assert.False(t, tree.GetTruncated())
+	assert.Equal(t, []*github.TreeEntry{
+		{Path: ptr(".gitattributes"), Type: ptr("blob")},
+		{Path: ptr("services"), Type: ptr("tree")},
+		{Path: ptr("services/.gitattributes"), Type: ptr("blob")},
+	}, tree.Entries)
+}
+
+func TestGitLabListIssueEvents(t *testing.T) {
+	t.Parallel()
+
+	provider := newTestGitLabProvider(t, &testGitLabAPI{})
+
+	events, _, err := provider.Issues().ListIssueEvents(t.Context(), "group", "project", 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*github.IssueEvent{
+		{Event: ptr("labeled"), Actor: &github.User{Login: ptr("maintainer"), Type: ptr("User")}, Label: &github.Label{Name: ptr("size-pinned:size::S")}},
+		{Event: ptr("unlabeled"), Actor: &github.User{Login: ptr("triager"), Type: ptr("User")}, Label: &github.Label{Name: ptr("size-pinned:size::S")}},
+	}, events)
+}
+
+func TestGitLabComments(t *testing.T) {
+	t.Parallel()
+
+	provider := newTestGitLabProvider(t, &testGitLabAPI{})
+
+	comments, _, err := provider.Issues().ListComments(t.Context(), "group", "project", 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*github.IssueComment{
+		{ID: ptr(int64(2)), Body: ptr("LGTM"), User: &github.User{Login: ptr("octocat"), Type: ptr("User")}},
+	}, comments)
+
+	user, _, err := provider.Users().Get(t.Context(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "project_7_bot", user.GetLogin())
+}
+
+func TestGitLabErrors(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(&testGitLabAPI{})
+	t.Cleanup(ts.Close)
+	provider := newGitLabProvider(ts.Client(), ts.URL+"/api/v4", "wrong-token", "7", "group", "project")
+
+	_, _, err := provider.Issues().ListLabels(t.Context(), "group", "project", nil)
+	var errResp *github.ErrorResponse
+	assert.ErrorAs(t, err, &errResp)
+	assert.Equal(t, http.StatusUnauthorized, errResp.Response.StatusCode)
+
+	_, _, err = provider.Checks().CreateCheckRun(t.Context(), "group", "project", github.CreateCheckRunOptions{})
+	assert.ErrorContains(t, err, "check runs aren't supported on GitLab")
+}
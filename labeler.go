@@ -3,12 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
-	"sort"
+	"path"
+	"strconv"
 
 	"github.com/google/go-github/v50/github"
 	"github.com/sethvargo/go-githubactions"
-	"k8s.io/test-infra/prow/gitattributes"
 )
 
 type IssuesClient interface {
@@ -17,34 +18,94 @@ type IssuesClient interface {
 	RemoveLabelForIssue(ctx context.Context, owner, repo string, number int, label string) (*github.Response, error)
 	CreateLabel(ctx context.Context, owner, repo string, label *github.Label) (*github.Label, *github.Response, error)
 	EditLabel(ctx context.Context, owner, repo, name string, label *github.Label) (*github.Label, *github.Response, error)
+	ListComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error)
+	CreateComment(ctx context.Context, owner, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error)
+	EditComment(ctx context.Context, owner, repo string, commentID int64, comment *github.IssueComment) (*github.IssueComment, *github.Response, error)
+	DeleteComment(ctx context.Context, owner, repo string, commentID int64) (*github.Response, error)
+	ListIssueEvents(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.IssueEvent, *github.Response, error)
 }
 
 type PullRequestsClient interface {
 	ListFiles(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.CommitFile, *github.Response, error)
+	List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
+	Get(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error)
+}
+
+type ChecksClient interface {
+	CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+}
+
+type RepositoriesClient interface {
+	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error)
+	CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error)
+	GetPermissionLevel(ctx context.Context, owner, repo, user string) (*github.RepositoryPermissionLevel, *github.Response, error)
+}
+
+type UsersClient interface {
+	Get(ctx context.Context, user string) (*github.User, *github.Response, error)
+}
+
+type GitClient interface {
+	GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error)
 }
 
 type GitHubPRSizeLabeler struct {
 	action       *githubactions.Action
 	issues       IssuesClient
 	pullRequests PullRequestsClient
-	event        LabelEvent
-
-	labels []Label `yaml:"labels"`
+	changes      ChangeSource
+	repositories RepositoriesClient
+	// gitData lists the trees of commits through the Git database API, not
+	// to be confused with git.
+	gitData GitClient
+	users   UsersClient
+	checks  ChecksClient
+	// owner and repo are the repository whose PRs are labeled.
+	owner string
+	repo  string
+	// git is the local checkout that large PRs are diffed in, or nil to only
+	// use the API.
+	git *gitRepo
+	// event is the PR being labeled, which is set by ForEvent.
+	event LabelEvent
+	// verifiedPin is a pin marker label known to have been added for someone
+	// who can override the size label, which needn't be looked up.
+	verifiedPin string
+	// plan records the changes that would have been made in a dry run, or is
+	// nil if this isn't one.
+	plan *dryRunPlan
+
+	config Config
 }
 
-func newGitHubPRSizeLabeler(issuesClient IssuesClient, pullRequestClient PullRequestsClient, action *githubactions.Action, labels []Label) (*GitHubPRSizeLabeler, error) {
-	event, err := getPREvent(action)
-	if err != nil {
-		return nil, err
-	}
-
+func newGitHubPRSizeLabeler(issuesClient IssuesClient, pullRequestClient PullRequestsClient, repositoriesClient RepositoriesClient, gitClient GitClient, usersClient UsersClient, checksClient ChecksClient, action *githubactions.Action, config Config, owner, repo string) *GitHubPRSizeLabeler {
 	return &GitHubPRSizeLabeler{
 		issues:       issuesClient,
 		pullRequests: pullRequestClient,
+		repositories: repositoriesClient,
+		gitData:      gitClient,
+		users:        usersClient,
+		checks:       checksClient,
 		action:       action,
+		owner:        owner,
+		repo:         repo,
+		git:          &gitRepo{},
+		config:       config,
+	}
+}
+
+// ForEvent returns a copy of the labeler that labels the PR of the given
+// event, sharing its clients and configuration.
+func (l *GitHubPRSizeLabeler) ForEvent(event LabelEvent) *GitHubPRSizeLabeler {
+	prLabeler := *l
+	prLabeler.event = event
+	prLabeler.changes = &githubChangeSource{
+		action:       l.action,
+		pullRequests: l.pullRequests,
 		event:        event,
-		labels:       labels,
-	}, nil
+		git:          l.git,
+	}
+	return &prLabeler
 }
 
 func (l *GitHubPRSizeLabeler) prHasLabel(label string) bool {
@@ -56,24 +117,104 @@ func (l *GitHubPRSizeLabeler) prHasLabel(label string) bool {
 	return false
 }
 
-func (l *GitHubPRSizeLabeler) loadGitAttributesFile() func() ([]byte, error) {
-	return func() ([]byte, error) {
-		content, err := fs.ReadFile(".gitattributes")
+// gitattributesLoader returns a gitattributesLoader for the PR's base commit.
+// On its first call, it lists the base commit's tree to find the .gitattributes
+// files, so that only those are fetched rather than one per directory. If the
+// tree can't be listed or is truncated, every directory is fetched.
+func (l *GitHubPRSizeLabeler) gitattributesLoader() gitattributesLoader {
+	var listed bool
+	var files map[string]bool
+	return func(ctx context.Context, dir string) ([]byte, bool, error) {
+		if !listed {
+			listed = true
+			files = l.listGitattributesFiles(ctx)
+		}
+		if files != nil && !files[path.Join(dir, ".gitattributes")] {
+			return nil, false, nil
+		}
+		return l.loadGitattributesFile(ctx, dir)
+	}
+}
+
+// listGitattributesFiles returns the paths of the .gitattributes files at the
+// PR's base commit, or nil if they can't all be listed.
+func (l *GitHubPRSizeLabeler) listGitattributesFiles(ctx context.Context) map[string]bool {
+	tree, _, err := l.gitData.GetTree(ctx, l.event.RepoOwner(), l.event.RepoName(), l.event.BaseSHA(), true)
+	if err != nil {
+		l.action.Warningf("Failed to list the files of base commit %s, fetching .gitattributes from every directory: %v", l.event.BaseSHA(), err)
+		return nil
+	}
+	if tree.GetTruncated() {
+		l.action.Debugf("The tree of base commit %s is too large to list, fetching .gitattributes from every directory", l.event.BaseSHA())
+		return nil
+	}
+
+	files := map[string]bool{}
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" && path.Base(entry.GetPath()) == ".gitattributes" {
+			files[entry.GetPath()] = true
+		}
+	}
+	return files
+}
+
+// loadGitattributesFile returns the contents of the .gitattributes file in dir
+// at the PR's base commit, fetched through the contents API so that it works
+// without a checkout. If the API request fails and local-gitattributes-fallback
+// is enabled, the file is read from the working directory instead. found is
+// false if there is no such file.
+func (l *GitHubPRSizeLabeler) loadGitattributesFile(ctx context.Context, dir string) (content []byte, found bool, err error) {
+	filePath := path.Join(dir, ".gitattributes")
+
+	file, _, resp, err := l.repositories.GetContents(ctx, l.event.RepoOwner(), l.event.RepoName(), filePath, &github.RepositoryContentGetOptions{
+		Ref: l.event.BaseSHA(),
+	})
+	switch {
+	case err == nil && file == nil:
+		// filePath is a directory
+		return nil, false, nil
+	case err == nil:
+		decoded, err := file.GetContent()
 		if err != nil {
-			l.action.Debugf("Successfully loaded .gitattributes file")
+			return nil, false, err
 		}
-		return content, err
+		l.action.Debugf("Loaded %s from base commit %s", filePath, l.event.BaseSHA())
+		return []byte(decoded), true, nil
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		return nil, false, nil
+	case !l.config.LocalGitattributesFallback:
+		return nil, false, fmt.Errorf("failed to fetch %s: %w", filePath, err)
 	}
+
+	l.action.Warningf("Failed to fetch %s, falling back to the local file: %v", filePath, err)
+	content, found, err = readLocalGitattributes(ctx, dir)
+	if found {
+		l.action.Debugf("Loaded local %s", filePath)
+	}
+	return content, found, err
 }
 
-func (l *GitHubPRSizeLabeler) hasGitattributesFile() bool {
-	_, err := fs.Stat(".gitattributes")
-	return !os.IsNotExist(err)
+// readLocalGitattributes is a gitattributesLoader that reads .gitattributes
+// files from the working directory.
+func readLocalGitattributes(_ context.Context, dir string) ([]byte, bool, error) {
+	content, err := fs.ReadFile(path.Join(dir, ".gitattributes"))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
 }
 
 // CreateSizeLabels creates or updates the configured size labels for the
 // repository.
 func (l *GitHubPRSizeLabeler) CreateSizeLabels(ctx context.Context) error {
+	if l.config.CheckRun.SkipLabels {
+		l.action.Infof("check-run.skip-labels is enabled, skipping creating size labels")
+		return nil
+	}
+
 	l.action.Group("Creating or Updating configured size labels for repository")
 	defer l.action.EndGroup()
 
@@ -82,12 +223,12 @@ func (l *GitHubPRSizeLabeler) CreateSizeLabels(ctx context.Context) error {
 		return err
 	}
 
-	for _, label := range l.labels {
+	for _, label := range l.config.Labels {
 		remoteLabel, ok := remoteLabels[label.Name]
 		if !ok {
 			l.action.Infof("Creating label %s", label.Name)
 			// label doesn't exist, create it
-			_, _, err := l.issues.CreateLabel(ctx, l.event.RepoOwner(), l.event.RepoName(), &github.Label{
+			_, _, err := l.issues.CreateLabel(ctx, l.owner, l.repo, &github.Label{
 				Name:        &label.Name,
 				Description: &label.Description,
 				Color:       &label.Color,
@@ -101,7 +242,7 @@ func (l *GitHubPRSizeLabeler) CreateSizeLabels(ctx context.Context) error {
 		// label exists, check if it needs to be updated
 		if !label.Matches(*remoteLabel) {
 			l.action.Infof("Label %s exists but is out of date, updating", label.Name)
-			_, _, err := l.issues.EditLabel(ctx, l.event.RepoOwner(), l.event.RepoName(), label.Name, &github.Label{
+			_, _, err := l.issues.EditLabel(ctx, l.owner, l.repo, label.Name, &github.Label{
 				Name:        &label.Name,
 				Description: &label.Description,
 				Color:       &label.Color,
@@ -119,56 +260,91 @@ func (l *GitHubPRSizeLabeler) CreateSizeLabels(ctx context.Context) error {
 
 // AddSizeLabel adds the appropriate size label to the PR based on the number of lines changed.
 // If the PR has a label that is no longer applicable, it will be removed.
-// If there is a .gitattributes file in the repository, linguist generated files will be ignored in
-// calculating the number of lines changed.
+// Files marked linguist-generated (when ignore-linguist-generated is enabled) or with any of the
+// other ignored attributes in the .gitattributes files at the PR's base commit will be ignored in
+// calculating the number of lines changed. Files matching any of the exclude-paths globs are
+// always ignored. Additions and deletions are scaled by addition-weight and deletion-weight,
+// combined according to count-mode, and lines in files matching a weights rule are multiplied
+// by that rule's weight. If comments are enabled, the PR comment explaining the size is updated
+// too, and a check run and commit status reporting the size are created if enabled. If the PR
+// exceeds limit.max-lines, an error is returned once labeling is done, unless the limit is
+// overridden or only set to warn.
 func (l *GitHubPRSizeLabeler) AddSizeLabel(ctx context.Context) error {
 	l.action.Group("Adding/Updating size label for PR")
 	defer l.action.EndGroup()
 
-	filesChanged, err := l.getPRFilesChanged(ctx)
+	size, err := l.calculateSize(ctx)
+	if err != nil {
+		return err
+	}
+
+	newLabel, previousLabel, err := l.selectLabel(ctx, size)
 	if err != nil {
 		return err
 	}
+	violation := l.checkLimit(size)
 
-	var ga *gitattributes.Group
+	l.setOutputs(size, newLabel, previousLabel)
+	l.addStepSummary(renderSummary(l.event.PRNumber(), size, l.config.Labels, newLabel, violation))
 
-	if !l.hasGitattributesFile() {
-		l.action.Infof("No .gitattributes file found, skipping linguist generated file checks")
-	} else {
-		ga, err = gitattributes.NewGroup(l.loadGitAttributesFile())
-		if err != nil {
+	if l.config.CheckRun.SkipLabels {
+		l.action.Infof("check-run.skip-labels is enabled, not labeling PR")
+	} else if err := l.updateLabels(ctx, newLabel); err != nil {
+		return err
+	}
+
+	if l.config.Comment.Enabled {
+		if err := l.UpdateComment(ctx, size, newLabel); err != nil {
 			return err
 		}
-		l.action.Infof("Ignoring linguist generated files based on .gitattributes file")
 	}
 
-	var linesChanged int
-	for _, change := range filesChanged {
-		if ga != nil && ga.IsLinguistGenerated(*change.Filename) {
-			l.action.Debugf("Skipping linguist generated file %s", *change.Filename)
-			continue
+	if l.config.CheckRun.Enabled {
+		if err := l.CreateCheckRun(ctx, size, newLabel, violation); err != nil {
+			return err
 		}
-		linesChanged += *change.Additions + *change.Deletions
 	}
 
-	l.action.Infof("Calculated PR %d has %d lines changed", l.event.PRNumber(), linesChanged)
+	if l.config.CommitStatus.Enabled {
+		if err := l.CreateCommitStatus(ctx, size, newLabel, violation); err != nil {
+			return err
+		}
+	}
 
-	sizeLabels := l.labels
-	// Sort the size labels from largest to smallest
-	sort.Slice(sizeLabels, func(i, j int) bool {
-		return sizeLabels[i].MinLines > sizeLabels[j].MinLines
-	})
+	if l.plan != nil {
+		l.addStepSummary(l.plan.render())
+	}
 
-	var newLabel string
-	// Find the first label in decreasing order that has a MinLines value less than the number of lines changed
-	// Also remove any labels that are no longer applicable
-	for _, label := range sizeLabels {
-		if newLabel == "" && linesChanged >= label.MinLines {
-			newLabel = label.Name
-			continue
-		}
+	return l.enforceLimit(violation)
+}
 
+// selectLabel returns the size label for a PR of the given size, which is the
+// largest label whose MinLines it reaches unless the label has been pinned,
+// and the largest size label the PR currently has.
+func (l *GitHubPRSizeLabeler) selectLabel(ctx context.Context, size prSize) (newLabel, previousLabel string, err error) {
+	for _, label := range l.config.sortedLabels() {
 		if l.prHasLabel(label.Name) {
+			previousLabel = label.Name
+			break
+		}
+	}
+
+	pinned, err := l.pinnedLabel(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if pinned != "" {
+		l.action.Infof("Size label is pinned to %s by a /size override command", pinned)
+		return pinned, previousLabel, nil
+	}
+	return l.config.labelFor(size), previousLabel, nil
+}
+
+// updateLabels adds the given size label to the PR and removes any other size
+// labels it has.
+func (l *GitHubPRSizeLabeler) updateLabels(ctx context.Context, newLabel string) error {
+	for _, label := range l.config.sortedLabels() {
+		if label.Name != newLabel && l.prHasLabel(label.Name) {
 			err := l.removeLabel(ctx, label.Name)
 			if err != nil {
 				l.action.Warningf("Failed to remove label %s: %v", label.Name, err)
@@ -184,6 +360,50 @@ func (l *GitHubPRSizeLabeler) AddSizeLabel(ctx context.Context) error {
 	return l.addLabel(ctx, newLabel)
 }
 
+// calculateSize calculates the size of the PR from the files it changes.
+func (l *GitHubPRSizeLabeler) calculateSize(ctx context.Context) (prSize, error) {
+	filesChanged, err := l.changes.FilesChanged(ctx)
+	if err != nil {
+		return prSize{}, err
+	}
+
+	s := &sizer{action: l.action, config: l.config, loadGitattributesFile: l.gitattributesLoader()}
+	size, err := s.Size(ctx, filesChanged)
+	if err != nil {
+		return prSize{}, err
+	}
+
+	l.action.Infof("Calculated PR %d has %d lines changed", l.event.PRNumber(), size.LinesCounted)
+	return size, nil
+}
+
+// addStepSummary adds the given Markdown to the job summary. Outside of GitHub
+// Actions there is no job summary, so this does nothing.
+func (l *GitHubPRSizeLabeler) addStepSummary(markdown string) {
+	if l.action.Getenv("GITHUB_STEP_SUMMARY") == "" {
+		l.action.Debugf("GITHUB_STEP_SUMMARY is not set, skipping job summary")
+		return
+	}
+	l.action.AddStepSummary(markdown)
+}
+
+// setOutputs sets the action outputs describing the PR's size so that later
+// steps can use them. Outside of GitHub Actions there is nowhere to write
+// outputs, so this does nothing.
+func (l *GitHubPRSizeLabeler) setOutputs(size prSize, label, previousLabel string) {
+	if l.action.Getenv("GITHUB_OUTPUT") == "" {
+		l.action.Debugf("GITHUB_OUTPUT is not set, skipping action outputs")
+		return
+	}
+
+	l.action.SetOutput("lines-changed", strconv.Itoa(size.LinesChanged))
+	l.action.SetOutput("lines-counted", strconv.Itoa(size.LinesCounted))
+	l.action.SetOutput("files-changed", strconv.Itoa(size.FilesChanged))
+	l.action.SetOutput("files-ignored", strconv.Itoa(size.FilesIgnored))
+	l.action.SetOutput("label", label)
+	l.action.SetOutput("previous-label", previousLabel)
+}
+
 // getAllLabels returns a map of all labels in the repository key'd by the label name.
 func (l *GitHubPRSizeLabeler) getAllLabels(ctx context.Context) (map[string]*github.Label, error) {
 	l.action.Infof("Getting all labels for repository")
@@ -191,7 +411,7 @@ func (l *GitHubPRSizeLabeler) getAllLabels(ctx context.Context) (map[string]*git
 	opts := &github.ListOptions{PerPage: 100}
 
 	for {
-		page, resp, err := l.issues.ListLabels(ctx, l.event.RepoOwner(), l.event.RepoName(), opts)
+		page, resp, err := l.issues.ListLabels(ctx, l.owner, l.repo, opts)
 		if err != nil {
 			return labels, err
 		}
@@ -211,34 +431,6 @@ func (l *GitHubPRSizeLabeler) getAllLabels(ctx context.Context) (map[string]*git
 	return labels, nil
 }
 
-func (l *GitHubPRSizeLabeler) getPRFilesChanged(ctx context.Context) ([]github.CommitFile, error) {
-	filesChanged := []github.CommitFile{}
-
-	l.action.Infof("Getting files changed in pr #%d", l.event.PRNumber())
-
-	opts := &github.ListOptions{PerPage: 100}
-
-	for {
-		page, resp, err := l.pullRequests.ListFiles(ctx, l.event.RepoOwner(), l.event.RepoName(), l.event.PRNumber(), opts)
-		if err != nil {
-			return filesChanged, err
-		}
-
-		for _, c := range page {
-			filesChanged = append(filesChanged, *c)
-		}
-
-		if resp.NextPage == 0 {
-			break
-		}
-
-		opts.Page = resp.NextPage
-	}
-
-	l.action.Infof("Found %d files changed in pr", len(filesChanged))
-	return filesChanged, nil
-}
-
 func (l *GitHubPRSizeLabeler) addLabel(ctx context.Context, label string) error {
 	l.action.Infof("Adding label %s to pr", label)
 	_, _, err := l.issues.AddLabelsToIssue(ctx, l.event.RepoOwner(), l.event.RepoName(), l.event.PRNumber(), []string{label})
@@ -251,12 +443,32 @@ func (l *GitHubPRSizeLabeler) removeLabel(ctx context.Context, label string) err
 	return err
 }
 
-func getPREvent(action *githubactions.Action) (LabelEvent, error) {
+// getPREvent returns the PR to label. The pr-number input takes precedence so
+// that sizing can be rerun manually, e.g. from a workflow_dispatch event.
+// Otherwise it's the PR of a pull request event, a PullRequestComment for an
+// issue comment made on a PR, or a MergeGroupEvent for the PR a merge group
+// was created for, whose PRs are fetched from the API. Events that have no PR
+// to label, like other merge_group actions, return an error wrapping
+// errNotApplicable.
+func getPREvent(ctx context.Context, action *githubactions.Action, pullRequests PullRequestsClient) (LabelEvent, error) {
 	ghContext, err := action.Context()
 	if err != nil {
 		return nil, err
 	}
 
+	if input := action.GetInput("pr-number"); input != "" {
+		number, err := strconv.Atoi(input)
+		if err != nil || number <= 0 {
+			return nil, fmt.Errorf("invalid pr-number input: %q", input)
+		}
+		owner, repo := ghContext.Repo()
+		pr, err := fetchPR(ctx, pullRequests, owner, repo, number)
+		if err != nil {
+			return nil, err
+		}
+		return FetchedPullRequest{pr: pr}, nil
+	}
+
 	payloadRaw, err := fs.ReadFile(ghContext.EventPath)
 	if err != nil {
 		return nil, err
@@ -273,7 +485,40 @@ func getPREvent(action *githubactions.Action) (LabelEvent, error) {
 		return PullRequestEvent{event: *event}, nil
 	case *github.PullRequestTargetEvent:
 		return PullRequestTargetEvent{event: *event}, nil
+	case *github.IssueCommentEvent:
+		if !event.GetIssue().IsPullRequest() {
+			return nil, fmt.Errorf("%w to issue #%d, which isn't a pull request", errNotApplicable, event.GetIssue().GetNumber())
+		}
+		pr, err := fetchPR(ctx, pullRequests, event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName(), event.GetIssue().GetNumber())
+		if err != nil {
+			return nil, err
+		}
+		return PullRequestComment{FetchedPullRequest: FetchedPullRequest{pr: pr}, event: *event}, nil
+	case *github.MergeGroupEvent:
+		if event.GetAction() != "checks_requested" {
+			return nil, fmt.Errorf("%w to merge_group %s events", errNotApplicable, event.GetAction())
+		}
+		headRef := event.GetMergeGroup().GetHeadRef()
+		number, ok := mergeGroupPRNumber(headRef)
+		if !ok {
+			return nil, fmt.Errorf("%w to merge group %s, which isn't for a PR", errNotApplicable, headRef)
+		}
+		pr, err := fetchPR(ctx, pullRequests, event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName(), number)
+		if err != nil {
+			return nil, err
+		}
+		return MergeGroupEvent{event: *event, pr: pr}, nil
 	default:
-		return nil, fmt.Errorf("event %T is not a pull request event", event)
+		return nil, fmt.Errorf("event %T is not a pull request event, set the pr-number input to label a PR", event)
+	}
+}
+
+// fetchPR fetches a PR from the API so that it can be labeled outside of a
+// pull request event.
+func fetchPR(ctx context.Context, pullRequests PullRequestsClient, owner, repo string, number int) (*github.PullRequest, error) {
+	pr, _, err := pullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch PR #%d: %w", number, err)
 	}
+	return pr, nil
 }
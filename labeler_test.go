@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-github/v50/github"
@@ -10,14 +14,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func newTestLabeler(event LabelEvent, labels []Label, issuesClient *mocks.IssuesClient, prClient *mocks.PullRequestsClient) *GitHubPRSizeLabeler {
-	return &GitHubPRSizeLabeler{
-		event:        event,
-		labels:       labels,
-		issues:       issuesClient,
-		pullRequests: prClient,
-		action:       githubactions.New(),
-	}
+func newTestLabeler(event LabelEvent, config Config, issuesClient *mocks.IssuesClient, prClient *mocks.PullRequestsClient, reposClient *mocks.RepositoriesClient) *GitHubPRSizeLabeler {
+	labeler := newGitHubPRSizeLabeler(issuesClient, prClient, reposClient, mocks.NewGitClient(reposClient), mocks.NewUsersClient(), mocks.NewChecksClient(), githubactions.New(), config, event.RepoOwner(), event.RepoName())
+	labeler.git = nil
+	return labeler.ForEvent(event)
 }
 
 func TestPrHasLabel(t *testing.T) {
@@ -160,9 +160,10 @@ func TestCreateSizeLabels(t *testing.T) {
 
 			labeler := newTestLabeler(
 				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
-				tt.configLabels,
+				Config{Labels: tt.configLabels},
 				mockIssues,
 				mocks.NewPullRequestsClient(),
+				mocks.NewRepositoriesClient(),
 			)
 
 			err := labeler.CreateSizeLabels(context.Background())
@@ -181,6 +182,9 @@ func TestAddSizeLabel(t *testing.T) {
 		currentLabels  []string
 		filesChanged   []*github.CommitFile
 		configLabels   []Label
+		excludePaths   []string
+		weights        []Weight
+		deletionWeight *float64
 		expectedAdd    string
 		expectedRemove []string
 	}{
@@ -254,6 +258,76 @@ func TestAddSizeLabel(t *testing.T) {
 			expectedAdd:    "",
 			expectedRemove: []string{},
 		},
+		{
+			name:          "exclude paths matching globs",
+			currentLabels: []string{},
+			filesChanged: []*github.CommitFile{
+				{Filename: ptr("file1.go"), Additions: ptr(3), Deletions: ptr(2)},
+				{Filename: ptr("api/v1/api.pb.go"), Additions: ptr(500), Deletions: ptr(0)},
+				{Filename: ptr("vendor/github.com/foo/bar.go"), Additions: ptr(200), Deletions: ptr(0)},
+				{Filename: ptr("pkg/parser/testdata/input.txt"), Additions: ptr(100), Deletions: ptr(50)},
+			},
+			configLabels: []Label{
+				{Name: "size/XS", MinLines: 0},
+				{Name: "size/S", MinLines: 10},
+				{Name: "size/M", MinLines: 100},
+			},
+			excludePaths:   []string{"**/*.pb.go", "vendor/**", "**/testdata/**"},
+			expectedAdd:    "size/XS",
+			expectedRemove: []string{},
+		},
+		{
+			name:          "weights scale lines in matching files",
+			currentLabels: []string{},
+			filesChanged: []*github.CommitFile{
+				{Filename: ptr("file1_test.go"), Additions: ptr(100), Deletions: ptr(0)},
+				{Filename: ptr("docs/guide.md"), Additions: ptr(40), Deletions: ptr(0)},
+				{Filename: ptr("file1.go"), Additions: ptr(10), Deletions: ptr(0)},
+			},
+			configLabels: []Label{
+				{Name: "size/XS", MinLines: 0},
+				{Name: "size/S", MinLines: 10},
+				{Name: "size/M", MinLines: 100},
+			},
+			weights: []Weight{
+				{Path: "**/*_test.go", Weight: 0.5},
+				{Path: "docs/**", Weight: 0.25},
+			},
+			expectedAdd:    "size/S",
+			expectedRemove: []string{},
+		},
+		{
+			name:          "weights can increase the size",
+			currentLabels: []string{},
+			filesChanged: []*github.CommitFile{
+				{Filename: ptr("migrations/0001_init.sql"), Additions: ptr(30), Deletions: ptr(20)},
+			},
+			configLabels: []Label{
+				{Name: "size/XS", MinLines: 0},
+				{Name: "size/S", MinLines: 10},
+				{Name: "size/M", MinLines: 100},
+			},
+			weights: []Weight{
+				{Path: "migrations/**", Weight: 2},
+			},
+			expectedAdd:    "size/M",
+			expectedRemove: []string{},
+		},
+		{
+			name:          "deletion weight shrinks pure deletion PRs",
+			currentLabels: []string{},
+			filesChanged: []*github.CommitFile{
+				{Filename: ptr("deadpkg/old.go"), Additions: ptr(0), Deletions: ptr(1000)},
+			},
+			configLabels: []Label{
+				{Name: "size/XS", MinLines: 0},
+				{Name: "size/S", MinLines: 10},
+				{Name: "size/M", MinLines: 100},
+			},
+			deletionWeight: ptr(0.05),
+			expectedAdd:    "size/S",
+			expectedRemove: []string{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -263,12 +337,14 @@ func TestAddSizeLabel(t *testing.T) {
 			mockIssues := mocks.NewIssuesClient()
 			mockPR := mocks.NewPullRequestsClient()
 			mockPR.FilesChanged = tt.filesChanged
+			mockRepos := mocks.NewRepositoriesClient()
 
 			labeler := newTestLabeler(
 				newTestGitHubPullRequestEvent(1, "repo", "owner", tt.currentLabels),
-				tt.configLabels,
+				Config{Labels: tt.configLabels, ExcludePaths: tt.excludePaths, Weights: tt.weights, DeletionWeight: tt.deletionWeight},
 				mockIssues,
 				mockPR,
+				mockRepos,
 			)
 
 			err := labeler.AddSizeLabel(t.Context())
@@ -284,3 +360,346 @@ func TestAddSizeLabel(t *testing.T) {
 		})
 	}
 }
+
+func TestAddSizeLabelIgnoreLinguistGenerated(t *testing.T) {
+	t.Parallel()
+
+	gitattributes := "*.gen.go linguist-generated=true\n"
+	filesChanged := []*github.CommitFile{
+		{Filename: ptr("file1.go"), Additions: ptr(3), Deletions: ptr(2)},
+		{Filename: ptr("client.gen.go"), Additions: ptr(100), Deletions: ptr(0)},
+	}
+	configLabels := []Label{
+		{Name: "size/XS", MinLines: 0},
+		{Name: "size/M", MinLines: 100},
+	}
+
+	tests := []struct {
+		name                    string
+		ignoreLinguistGenerated bool
+		expectedAdd             string
+	}{
+		{
+			name:                    "linguist generated files are ignored when enabled",
+			ignoreLinguistGenerated: true,
+			expectedAdd:             "size/XS",
+		},
+		{
+			name:                    "linguist generated files are counted when disabled",
+			ignoreLinguistGenerated: false,
+			expectedAdd:             "size/M",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = filesChanged
+			mockRepos := mocks.NewRepositoriesClient()
+			mockRepos.Files[".gitattributes"] = gitattributes
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				Config{Labels: configLabels, IgnoreLinguistGenerated: tt.ignoreLinguistGenerated},
+				mockIssues,
+				mockPR,
+				mockRepos,
+			)
+
+			err := labeler.AddSizeLabel(t.Context())
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.expectedAdd}, mockIssues.AddedLabels)
+		})
+	}
+}
+
+func TestAddSizeLabelIgnoreAttributes(t *testing.T) {
+	t.Parallel()
+
+	gitattributes := "vendor/** linguist-vendored\ndocs/** linguist-documentation\nfixtures/** pr-size-ignore\n"
+	filesChanged := []*github.CommitFile{
+		{Filename: ptr("file1.go"), Additions: ptr(3), Deletions: ptr(2)},
+		{Filename: ptr("vendor/github.com/foo/bar.go"), Additions: ptr(100), Deletions: ptr(0)},
+		{Filename: ptr("docs/guide.md"), Additions: ptr(100), Deletions: ptr(0)},
+		{Filename: ptr("fixtures/input.json"), Additions: ptr(100), Deletions: ptr(0)},
+	}
+	configLabels := []Label{
+		{Name: "size/XS", MinLines: 0},
+		{Name: "size/M", MinLines: 100},
+		{Name: "size/L", MinLines: 200},
+		{Name: "size/XL", MinLines: 300},
+	}
+
+	tests := []struct {
+		name        string
+		config      Config
+		expectedAdd string
+	}{
+		{
+			name:        "attributes are counted by default",
+			config:      Config{},
+			expectedAdd: "size/XL",
+		},
+		{
+			name:        "linguist vendored files are ignored",
+			config:      Config{IgnoreLinguistVendored: true},
+			expectedAdd: "size/L",
+		},
+		{
+			name:        "linguist documentation files are ignored",
+			config:      Config{IgnoreLinguistDocumentation: true},
+			expectedAdd: "size/L",
+		},
+		{
+			name:        "files with custom attributes are ignored",
+			config:      Config{IgnoreAttributes: []string{"pr-size-ignore"}},
+			expectedAdd: "size/L",
+		},
+		{
+			name: "all attributes are ignored",
+			config: Config{
+				IgnoreLinguistVendored:      true,
+				IgnoreLinguistDocumentation: true,
+				IgnoreAttributes:            []string{"pr-size-ignore"},
+			},
+			expectedAdd: "size/XS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = filesChanged
+			mockRepos := mocks.NewRepositoriesClient()
+			mockRepos.Files[".gitattributes"] = gitattributes
+
+			tt.config.Labels = configLabels
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				tt.config,
+				mockIssues,
+				mockPR,
+				mockRepos,
+			)
+
+			err := labeler.AddSizeLabel(t.Context())
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.expectedAdd}, mockIssues.AddedLabels)
+		})
+	}
+}
+
+func TestAddSizeLabelNestedGitattributes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		truncated       bool
+		getTreeErr      error
+		expectedFetches int
+	}{
+		{
+			name:            "only fetches the files in the tree",
+			expectedFetches: 2,
+		},
+		{
+			name:            "fetches every directory when the tree is truncated",
+			truncated:       true,
+			expectedFetches: 5,
+		},
+		{
+			name:            "fetches every directory when the tree can't be listed",
+			getTreeErr:      errors.New("boom"),
+			expectedFetches: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = []*github.CommitFile{
+				{Filename: ptr("main.go"), Additions: ptr(3), Deletions: ptr(2)},
+				{Filename: ptr("services/api/client/client.go"), Additions: ptr(100), Deletions: ptr(0)},
+				{Filename: ptr("services/web/api.pb.go"), Additions: ptr(100), Deletions: ptr(0)},
+			}
+			mockRepos := mocks.NewRepositoriesClient()
+			mockRepos.Files[".gitattributes"] = "*.pb.go linguist-generated=true\n"
+			mockRepos.Files["services/api/.gitattributes"] = "client/** linguist-generated=true\n"
+			mockGit := mocks.NewGitClient(mockRepos)
+			mockGit.Truncated = tt.truncated
+			mockGit.GetTreeErr = tt.getTreeErr
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				Config{
+					IgnoreLinguistGenerated: true,
+					Labels: []Label{
+						{Name: "size/XS", MinLines: 0},
+						{Name: "size/M", MinLines: 100},
+					},
+				},
+				mockIssues,
+				mockPR,
+				mockRepos,
+			)
+			labeler.gitData = mockGit
+
+			err := labeler.AddSizeLabel(t.Context())
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"size/XS"}, mockIssues.AddedLabels)
+			assert.Equal(t, []string{testBaseSHA}, mockGit.GetTreeSHAs)
+			assert.Len(t, mockRepos.GetContentsRefs, tt.expectedFetches)
+		})
+	}
+}
+
+func TestAddSizeLabelSetsOutputsAndSummary(t *testing.T) {
+	t.Parallel()
+
+	outputPath := filepath.Join(t.TempDir(), "output")
+	summaryPath := filepath.Join(t.TempDir(), "summary")
+	action := githubactions.New(githubactions.WithGetenv(func(key string) string {
+		switch key {
+		case "GITHUB_OUTPUT":
+			return outputPath
+		case "GITHUB_STEP_SUMMARY":
+			return summaryPath
+		}
+		return ""
+	}))
+
+	mockIssues := mocks.NewIssuesClient()
+	mockPR := mocks.NewPullRequestsClient()
+	mockPR.FilesChanged = []*github.CommitFile{
+		{Filename: ptr("file1.go"), Additions: ptr(80), Deletions: ptr(30)},
+		{Filename: ptr("vendor/foo/bar.go"), Additions: ptr(200), Deletions: ptr(0)},
+		{Filename: ptr("logo.png"), Additions: ptr(0), Deletions: ptr(0), Status: ptr("modified")},
+	}
+
+	labeler := newTestLabeler(
+		newTestGitHubPullRequestEvent(1, "repo", "owner", []string{"size/S"}),
+		Config{
+			ExcludePaths: []string{"vendor/**"},
+			Labels: []Label{
+				{Name: "size/XS", MinLines: 0},
+				{Name: "size/S", MinLines: 10},
+				{Name: "size/M", MinLines: 100},
+			},
+		},
+		mockIssues,
+		mockPR,
+		mocks.NewRepositoriesClient(),
+	)
+	labeler.action = action
+
+	err := labeler.AddSizeLabel(t.Context())
+	assert.NoError(t, err)
+
+	output, err := os.ReadFile(outputPath)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"lines-changed":  "310",
+		"lines-counted":  "110",
+		"files-changed":  "3",
+		"files-ignored":  "2",
+		"label":          "size/M",
+		"previous-label": "size/S",
+	}, parseOutputs(string(output)))
+
+	summary, err := os.ReadFile(summaryPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(summary), "PR #1 has **110** lines changed and is labeled `size/M`.")
+	assert.Contains(t, string(summary), "| `vendor/foo/bar.go` | 200 | 0 | 0 | excluded by `vendor/**` |")
+	assert.Contains(t, string(summary), "| `logo.png` | 0 | 0 | 0 | binary |")
+}
+
+// parseOutputs parses the outputs written to GITHUB_OUTPUT by SetOutput.
+func parseOutputs(output string) map[string]string {
+	outputs := map[string]string{}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for i := 0; i+2 < len(lines); i += 3 {
+		name, _, _ := strings.Cut(lines[i], "<<")
+		outputs[name] = lines[i+1]
+	}
+	return outputs
+}
+
+func TestLoadGitattributesFile(t *testing.T) {
+	useMemMapFs(t)
+
+	err := fs.WriteFile(".gitattributes", []byte("local linguist-generated=true\n"), 0644)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		remoteFile      *string
+		remoteErr       error
+		fallback        bool
+		expectedContent string
+		expectedFound   bool
+		expectedErr     bool
+	}{
+		{
+			name:            "loads file from base commit",
+			remoteFile:      ptr("remote linguist-generated=true\n"),
+			fallback:        true,
+			expectedContent: "remote linguist-generated=true\n",
+			expectedFound:   true,
+		},
+		{
+			name:          "missing remote file doesn't fall back to local file",
+			fallback:      true,
+			expectedFound: false,
+		},
+		{
+			name:            "falls back to local file when API request fails",
+			remoteErr:       errors.New("boom"),
+			fallback:        true,
+			expectedContent: "local linguist-generated=true\n",
+			expectedFound:   true,
+		},
+		{
+			name:        "returns error when API request fails without fallback",
+			remoteErr:   errors.New("boom"),
+			fallback:    false,
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepos := mocks.NewRepositoriesClient()
+			mockRepos.GetContentsErr = tt.remoteErr
+			if tt.remoteFile != nil {
+				mockRepos.Files[".gitattributes"] = *tt.remoteFile
+			}
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				Config{LocalGitattributesFallback: tt.fallback},
+				mocks.NewIssuesClient(),
+				mocks.NewPullRequestsClient(),
+				mockRepos,
+			)
+
+			content, found, err := labeler.loadGitattributesFile(t.Context(), "")
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expectedContent, string(content))
+			assert.Equal(t, []string{testBaseSHA}, mockRepos.GetContentsRefs)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkLimit returns a description of how the PR's size breaks the configured
+// limit, or an empty string if it doesn't or the limit has been overridden.
+func (l *GitHubPRSizeLabeler) checkLimit(size prSize) string {
+	limit := l.config.Limit
+	if limit.MaxLines == 0 || size.LinesCounted <= limit.MaxLines {
+		return ""
+	}
+
+	if limit.OverrideLabel != "" && l.prHasLabel(limit.OverrideLabel) {
+		l.action.Infof("PR exceeds the limit of %d lines but has override label %s", limit.MaxLines, limit.OverrideLabel)
+		return ""
+	}
+	if limit.OverrideMarker != "" && strings.Contains(l.event.PRBody(), limit.OverrideMarker) {
+		l.action.Infof("PR exceeds the limit of %d lines but its body contains override marker %q", limit.MaxLines, limit.OverrideMarker)
+		return ""
+	}
+
+	return fmt.Sprintf("PR #%d has %d lines changed, exceeding the limit of %d set by limit.max-lines",
+		l.event.PRNumber(), size.LinesCounted, limit.MaxLines)
+}
+
+// enforceLimit fails with the given limit violation, or only warns about it
+// if the limit's mode is warn or this is a dry run.
+func (l *GitHubPRSizeLabeler) enforceLimit(violation string) error {
+	if violation == "" {
+		return nil
+	}
+
+	if l.config.Limit.Mode == LimitModeWarn {
+		l.action.Warningf("%s", violation)
+		return nil
+	}
+	if l.plan != nil {
+		l.action.Warningf("[dry-run] Would fail: %s", violation)
+		return nil
+	}
+	return fmt.Errorf("%s", violation)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		limit             LimitConfig
+		linesCounted      int
+		prLabels          []string
+		prBody            string
+		expectedViolation string
+	}{
+		{
+			name:              "no limit configured",
+			limit:             LimitConfig{},
+			linesCounted:      5000,
+			expectedViolation: "",
+		},
+		{
+			name:              "within the limit",
+			limit:             LimitConfig{MaxLines: 1000},
+			linesCounted:      1000,
+			expectedViolation: "",
+		},
+		{
+			name:              "exceeds the limit",
+			limit:             LimitConfig{MaxLines: 1000},
+			linesCounted:      1001,
+			expectedViolation: "PR #1 has 1001 lines changed, exceeding the limit of 1000 set by limit.max-lines",
+		},
+		{
+			name:              "overridden by label",
+			limit:             LimitConfig{MaxLines: 1000, OverrideLabel: "size/override-approved"},
+			linesCounted:      5000,
+			prLabels:          []string{"size/override-approved"},
+			expectedViolation: "",
+		},
+		{
+			name:              "overridden by body marker",
+			limit:             LimitConfig{MaxLines: 1000, OverrideMarker: "<!-- size-override -->"},
+			linesCounted:      5000,
+			prBody:            "Big generated migration.\n<!-- size-override -->",
+			expectedViolation: "",
+		},
+		{
+			name:              "override label must be on the PR",
+			limit:             LimitConfig{MaxLines: 1000, OverrideLabel: "size/override-approved", OverrideMarker: "size-override"},
+			linesCounted:      5000,
+			prLabels:          []string{"bug"},
+			prBody:            "No marker here",
+			expectedViolation: "PR #1 has 5000 lines changed, exceeding the limit of 1000 set by limit.max-lines",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			event := newTestGitHubPullRequestEvent(1, "repo", "owner", tt.prLabels)
+			event.event.PullRequest.Body = ptr(tt.prBody)
+
+			labeler := newTestLabeler(
+				event,
+				Config{Limit: tt.limit},
+				mocks.NewIssuesClient(),
+				mocks.NewPullRequestsClient(),
+				mocks.NewRepositoriesClient(),
+			)
+
+			assert.Equal(t, tt.expectedViolation, labeler.checkLimit(prSize{LinesCounted: tt.linesCounted}))
+		})
+	}
+}
+
+func TestAddSizeLabelEnforcesLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		mode        LimitMode
+		expectedErr bool
+	}{
+		{
+			name:        "fails when exceeding the limit",
+			mode:        LimitModeFail,
+			expectedErr: true,
+		},
+		{
+			name:        "fails by default when exceeding the limit",
+			expectedErr: true,
+		},
+		{
+			name:        "only warns in warn mode",
+			mode:        LimitModeWarn,
+			expectedErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockIssues := mocks.NewIssuesClient()
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = []*github.CommitFile{
+				{Filename: ptr("file1.go"), Additions: ptr(1500), Deletions: ptr(0)},
+			}
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				Config{
+					Limit: LimitConfig{MaxLines: 1000, Mode: tt.mode},
+					Labels: []Label{
+						{Name: "size/S", MinLines: 0},
+						{Name: "size/XL", MinLines: 1000},
+					},
+				},
+				mockIssues,
+				mockPR,
+				mocks.NewRepositoriesClient(),
+			)
+
+			err := labeler.AddSizeLabel(t.Context())
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			// The PR is labeled even when it breaks the limit
+			assert.Equal(t, []string{"size/XL"}, mockIssues.AddedLabels)
+		})
+	}
+}
@@ -2,19 +2,29 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
 
 	"github.com/google/go-github/v50/github"
 	"github.com/sethvargo/go-githubactions"
 )
 
 func main() {
-	action := githubactions.New()
-
-	repoToken := action.GetInput("repo-token")
-	if repoToken == "" {
-		action.Fatalf("missing required input: repo-token")
+	if len(os.Args) > 1 && os.Args[1] == "size" {
+		os.Exit(runSize(context.Background(), os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(context.Background(), os.Args[2:], os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "gitlab" {
+		os.Exit(runGitLab(context.Background(), os.Args[2:], os.Stdout, os.Stderr))
 	}
 
+	action := githubactions.New()
+
 	configPath := action.GetInput("config-path")
 	if configPath == "" {
 		action.Fatalf("missing required input: config-path")
@@ -25,19 +35,202 @@ func main() {
 		action.Fatalf("%v", err)
 	}
 
+	dryRun, err := getBoolInput(action, "dry-run", false)
+	if err != nil {
+		action.Fatalf("%v", err)
+	}
+
+	mode := action.GetInput("mode")
+	if mode == "" {
+		mode = "pr"
+	}
+	if mode != "pr" && mode != "backfill" {
+		action.Fatalf("invalid mode input: %q", mode)
+	}
+
+	ghContext, err := action.Context()
+	if err != nil {
+		action.Fatalf("%v", err)
+	}
+	owner, repo := ghContext.Repo()
+
+	apiURL := action.GetInput("api-url")
+	if apiURL == "" {
+		apiURL = ghContext.APIURL
+	}
+
 	ctx := context.Background()
-	client := github.NewTokenClient(ctx, repoToken)
+	provider, err := newActionProvider(ctx, action, apiURL, owner, repo)
+	if err != nil {
+		action.Fatalf("%v", err)
+	}
+
+	labeler := newProviderLabeler(provider, action, config, owner, repo)
+	if dryRun {
+		labeler.DryRun()
+	}
 
-	labeler, err := newGitHubPRSizeLabeler(client.Issues, client.PullRequests, action, config.Labels)
+	if mode == "backfill" {
+		closedWithinDays, err := getIntInput(action, "backfill-closed-days", 0)
+		if err != nil {
+			action.Fatalf("%v", err)
+		}
+		concurrency, err := getIntInput(action, "backfill-concurrency", 4)
+		if err != nil {
+			action.Fatalf("%v", err)
+		}
+
+		if err := labeler.CreateSizeLabels(ctx); err != nil {
+			action.Fatalf("%v", err)
+		}
+
+		err = labeler.Backfill(ctx, BackfillOptions{ClosedWithinDays: closedWithinDays, Concurrency: concurrency})
+		if err != nil {
+			action.Fatalf("%v", err)
+		}
+		return
+	}
+
+	event, err := getPREvent(ctx, action, provider.PullRequests())
+	if errors.Is(err, errNotApplicable) {
+		action.Noticef("%v, skipping", err)
+		return
+	}
 	if err != nil {
 		action.Fatalf("%v", err)
 	}
 
+	if _, ok := event.(MergeGroupEvent); ok {
+		if err := labeler.ForEvent(event).VerifySize(ctx); err != nil {
+			action.Fatalf("%v", err)
+		}
+		return
+	}
+
+	if comment, ok := event.(PullRequestComment); ok {
+		if command, ok := parseSizeCommand(comment.CommentBody()); ok && comment.CommentAction() == "created" {
+			if err := labeler.CreateSizeLabels(ctx); err != nil {
+				action.Fatalf("%v", err)
+			}
+			if err := labeler.ForEvent(event).RunCommand(ctx, command, comment.CommentAuthor()); err != nil {
+				action.Fatalf("%v", err)
+			}
+			return
+		}
+
+		// Other comments label the PR like any other event, unless the
+		// workflow only runs for /size commands
+		labelOnComment, err := getBoolInput(action, "label-on-comment", true)
+		if err != nil {
+			action.Fatalf("%v", err)
+		}
+		if !labelOnComment {
+			action.Infof("Comment isn't a new /size command, skipping")
+			return
+		}
+	}
+
 	if err := labeler.CreateSizeLabels(ctx); err != nil {
 		action.Fatalf("%v", err)
 	}
 
-	if err := labeler.AddSizeLabel(ctx); err != nil {
+	if err := labeler.ForEvent(event).AddSizeLabel(ctx); err != nil {
 		action.Fatalf("%v", err)
 	}
 }
+
+// getBoolInput returns the value of a boolean input, or fallback if it isn't
+// set.
+func getBoolInput(action *githubactions.Action, name string, fallback bool) (bool, error) {
+	input := action.GetInput(name)
+	if input == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseBool(input)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s input: %q", name, input)
+	}
+	return value, nil
+}
+
+// getIntInput returns the value of a non-negative integer input, or fallback
+// if it isn't set.
+func getIntInput(action *githubactions.Action, name string, fallback int) (int, error) {
+	input := action.GetInput(name)
+	if input == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(input)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s input: %q", name, input)
+	}
+	return value, nil
+}
+
+// newActionProvider returns the provider for the code host the action is
+// running on, with its API at apiURL. On Gitea and Forgejo Actions, whose event
+// payloads are the same as GitHub's, the Gitea provider authenticates with the
+// repo-token input.
+func newActionProvider(ctx context.Context, action *githubactions.Action, apiURL, owner, repo string) (Provider, error) {
+	if runningOnGitea(action.Getenv) {
+		repoToken := action.GetInput("repo-token")
+		if repoToken == "" {
+			return nil, errors.New("missing required input: repo-token")
+		}
+		newClient, err := newGiteaClientFunc(apiURL)
+		if err != nil {
+			return nil, err
+		}
+		return newGiteaProvider(newTokenClient(ctx, newClient, repoToken)), nil
+	}
+
+	newClient, err := newGitHubClientFunc(apiURL, action.GetInput("upload-url"))
+	if err != nil {
+		return nil, err
+	}
+	client, err := newActionClient(ctx, action, newClient, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	return githubProvider{client: client}, nil
+}
+
+// newActionClient returns a client authenticated as the GitHub App given by
+// the app-id and app-private-key inputs, using its installation on the
+// repository, or with the repo-token input if app-id isn't set. Clients are
+// created by newClient.
+func newActionClient(ctx context.Context, action *githubactions.Action, newClient func(*http.Client) *github.Client, owner, repo string) (*github.Client, error) {
+	appID, err := getIntInput(action, "app-id", 0)
+	if err != nil {
+		return nil, err
+	}
+	if appID == 0 {
+		repoToken := action.GetInput("repo-token")
+		if repoToken == "" {
+			return nil, errors.New("missing required input: repo-token")
+		}
+		return newTokenClient(ctx, newClient, repoToken), nil
+	}
+
+	privateKey := action.GetInput("app-private-key")
+	if privateKey == "" {
+		return nil, errors.New("missing required input: app-private-key")
+	}
+	app, err := newGitHubApp(int64(appID), []byte(privateKey), newClient)
+	if err != nil {
+		return nil, err
+	}
+
+	installationID, err := getIntInput(action, "app-installation-id", 0)
+	if err != nil {
+		return nil, err
+	}
+	if installationID == 0 {
+		id, err := app.repositoryInstallation(ctx, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		return app.installationClient(id), nil
+	}
+	return app.installationClient(int64(installationID)), nil
+}
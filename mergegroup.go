@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+// errNotApplicable is returned for events that have no PR to label, which
+// aren't failures.
+var errNotApplicable = errors.New("labeling is not applicable")
+
+// mergeQueueRefPattern matches the head ref of a merge group, e.g.
+// refs/heads/gh-readonly-queue/main/pr-123-6dcb09b5b57875f334f61aebed695e2e4193db5e,
+// capturing the number of the PR that the merge group was created for.
+var mergeQueueRefPattern = regexp.MustCompile(`^refs/heads/gh-readonly-queue/.+/pr-(\d+)-[0-9a-f]+$`)
+
+// mergeGroupPRNumber returns the number of the PR that the merge group with
+// the given head ref was created for. The PRs ahead of it in the queue have
+// merge groups of their own, so its base to head diff only contains this PR.
+func mergeGroupPRNumber(headRef string) (int, bool) {
+	match := mergeQueueRefPattern.FindStringSubmatch(headRef)
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.Atoi(match[1])
+	return number, err == nil
+}
+
+// VerifySize checks the PR's size against the configured policy without
+// relabeling or commenting on it, for merge groups, whose PRs were labeled by
+// their pull request events. The check run and commit status are created on
+// the merge group's commit if enabled, so that they can be required by the
+// merge queue, and an error is returned if the PR exceeds limit.max-lines.
+func (l *GitHubPRSizeLabeler) VerifySize(ctx context.Context) error {
+	l.action.Group("Verifying size of PR in merge group")
+	defer l.action.EndGroup()
+
+	size, err := l.calculateSize(ctx)
+	if err != nil {
+		return err
+	}
+
+	newLabel, previousLabel, err := l.selectLabel(ctx, size)
+	if err != nil {
+		return err
+	}
+	violation := l.checkLimit(size)
+
+	l.setOutputs(size, newLabel, previousLabel)
+	l.addStepSummary(renderSummary(l.event.PRNumber(), size, l.config.Labels, newLabel, violation))
+
+	if l.config.CheckRun.Enabled {
+		if err := l.CreateCheckRun(ctx, size, newLabel, violation); err != nil {
+			return err
+		}
+	}
+
+	if l.config.CommitStatus.Enabled {
+		if err := l.CreateCommitStatus(ctx, size, newLabel, violation); err != nil {
+			return err
+		}
+	}
+
+	if l.plan != nil {
+		l.addStepSummary(l.plan.render())
+	}
+
+	return l.enforceLimit(violation)
+}
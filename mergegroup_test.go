@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeGroupPRNumber(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		headRef        string
+		expectedNumber int
+		expectedOK     bool
+	}{
+		{
+			name:           "merge queue ref",
+			headRef:        "refs/heads/gh-readonly-queue/main/pr-123-6dcb09b5b57875f334f61aebed695e2e4193db5e",
+			expectedNumber: 123,
+			expectedOK:     true,
+		},
+		{
+			name:           "base branch with a slash",
+			headRef:        "refs/heads/gh-readonly-queue/release/v1/pr-7-e5bd3914e2e596debea16f433f57875b5b90bcd6",
+			expectedNumber: 7,
+			expectedOK:     true,
+		},
+		{
+			name:       "other branch",
+			headRef:    "refs/heads/pr-123-6dcb09b5b57875f334f61aebed695e2e4193db5e",
+			expectedOK: false,
+		},
+		{
+			name:       "empty ref",
+			headRef:    "",
+			expectedOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			number, ok := mergeGroupPRNumber(tc.headRef)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedNumber, number)
+		})
+	}
+}
+
+func TestVerifySize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name               string
+		limit              LimitConfig
+		expectedConclusion string
+		expectedErr        bool
+	}{
+		{
+			name:               "within the limit",
+			limit:              LimitConfig{MaxLines: 1000},
+			expectedConclusion: "success",
+		},
+		{
+			name:               "exceeds the limit",
+			limit:              LimitConfig{MaxLines: 100},
+			expectedConclusion: "failure",
+			expectedErr:        true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			pr := newTestPullRequest(1, "open", []string{"size/S"}, 0)
+			event := MergeGroupEvent{
+				event: github.MergeGroupEvent{
+					Action: ptr("checks_requested"),
+					MergeGroup: &github.MergeGroup{
+						HeadSHA: ptr("merge-group-head"),
+						BaseSHA: ptr("merge-group-base"),
+						HeadRef: ptr("refs/heads/gh-readonly-queue/main/pr-1-" + testHeadSHA),
+					},
+					Repo: &github.Repository{Name: ptr("repo"), Owner: &github.User{Login: ptr("owner")}},
+				},
+				pr: pr,
+			}
+
+			mockIssues := mocks.NewIssuesClient()
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = []*github.CommitFile{{Filename: ptr("main.go"), Additions: ptr(150), Deletions: ptr(0), Patch: ptr("")}}
+			mockRepos := mocks.NewRepositoriesClient()
+			mockRepos.Files[".gitattributes"] = "*.pb.go linguist-generated=true\n"
+			mockChecks := mocks.NewChecksClient()
+
+			labeler := newTestLabeler(
+				event,
+				Config{
+					IgnoreLinguistGenerated: true,
+					Comment:                 CommentConfig{Enabled: true},
+					CheckRun:                CheckRunConfig{Enabled: true},
+					CommitStatus:            CommitStatusConfig{Enabled: true},
+					Limit:                   tc.limit,
+					Labels:                  []Label{{Name: "size/S", MinLines: 0}, {Name: "size/L", MinLines: 100}},
+				},
+				mockIssues,
+				mockPR,
+				mockRepos,
+			)
+			labeler.checks = mockChecks
+
+			err := labeler.VerifySize(t.Context())
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Empty(t, mockIssues.AddedLabels)
+			assert.Empty(t, mockIssues.RemovedLabels)
+			assert.Empty(t, mockIssues.CreatedComments)
+			assert.Contains(t, mockRepos.GetContentsRefs, "merge-group-base")
+			if assert.Len(t, mockChecks.CreatedCheckRuns, 1) {
+				assert.Equal(t, "merge-group-head", mockChecks.CreatedCheckRuns[0].HeadSHA)
+				assert.Equal(t, tc.expectedConclusion, mockChecks.CreatedCheckRuns[0].GetConclusion())
+			}
+			assert.Len(t, mockRepos.CreatedStatuses["merge-group-head"], 1)
+		})
+	}
+}
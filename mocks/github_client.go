@@ -2,33 +2,61 @@ package mocks
 
 import (
 	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
 
 	"github.com/google/go-github/v50/github"
 )
 
 type IssuesClient struct {
-	Labels         map[string]*github.Label
-	CreatedLabels  []*github.Label
-	EditedLabels   []*github.Label
-	AddedLabels    []string
-	RemovedLabels  []string
-	CreateLabelErr error
-	EditLabelErr   error
-	AddLabelsErr   error
-	RemoveLabelErr error
+	mu sync.Mutex
+
+	Labels          map[string]*github.Label
+	CreatedLabels   []*github.Label
+	EditedLabels    []*github.Label
+	AddedLabels     []string
+	RemovedLabels   []string
+	Comments        []*github.IssueComment
+	CreatedComments []*github.IssueComment
+	EditedComments  []*github.IssueComment
+	DeletedComments []int64
+	CreateLabelErr  error
+	EditLabelErr    error
+	AddLabelsErr    error
+	RemoveLabelErr  error
+
+	// Events are the events of every issue
+	Events []*github.IssueEvent
+
+	// AddedLabelsByIssue and RemovedLabelsByIssue map an issue number to the
+	// labels added to or removed from it
+	AddedLabelsByIssue   map[int][]string
+	RemovedLabelsByIssue map[int][]string
 }
 
 func NewIssuesClient() *IssuesClient {
 	return &IssuesClient{
-		Labels:        make(map[string]*github.Label),
-		CreatedLabels: []*github.Label{},
-		EditedLabels:  []*github.Label{},
-		AddedLabels:   []string{},
-		RemovedLabels: []string{},
+		Labels:          make(map[string]*github.Label),
+		CreatedLabels:   []*github.Label{},
+		EditedLabels:    []*github.Label{},
+		AddedLabels:     []string{},
+		RemovedLabels:   []string{},
+		Comments:        []*github.IssueComment{},
+		CreatedComments: []*github.IssueComment{},
+		EditedComments:  []*github.IssueComment{},
+		DeletedComments: []int64{},
+
+		AddedLabelsByIssue:   make(map[int][]string),
+		RemovedLabelsByIssue: make(map[int][]string),
 	}
 }
 
 func (m *IssuesClient) ListLabels(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	labels := []*github.Label{}
 	for _, label := range m.Labels {
 		labels = append(labels, label)
@@ -37,6 +65,9 @@ func (m *IssuesClient) ListLabels(ctx context.Context, owner, repo string, opts
 }
 
 func (m *IssuesClient) CreateLabel(ctx context.Context, owner, repo string, label *github.Label) (*github.Label, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.CreateLabelErr != nil {
 		return nil, nil, m.CreateLabelErr
 	}
@@ -46,6 +77,9 @@ func (m *IssuesClient) CreateLabel(ctx context.Context, owner, repo string, labe
 }
 
 func (m *IssuesClient) EditLabel(ctx context.Context, owner, repo, name string, label *github.Label) (*github.Label, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.EditLabelErr != nil {
 		return nil, nil, m.EditLabelErr
 	}
@@ -55,10 +89,14 @@ func (m *IssuesClient) EditLabel(ctx context.Context, owner, repo, name string,
 }
 
 func (m *IssuesClient) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.AddLabelsErr != nil {
 		return nil, nil, m.AddLabelsErr
 	}
 	m.AddedLabels = append(m.AddedLabels, labels...)
+	m.AddedLabelsByIssue[number] = append(m.AddedLabelsByIssue[number], labels...)
 	result := []*github.Label{}
 	for _, label := range labels {
 		labelCopy := label
@@ -67,24 +105,239 @@ func (m *IssuesClient) AddLabelsToIssue(ctx context.Context, owner, repo string,
 	return result, &github.Response{}, nil
 }
 
+func (m *IssuesClient) ListIssueEvents(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.IssueEvent, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.Events, &github.Response{}, nil
+}
+
 func (m *IssuesClient) RemoveLabelForIssue(ctx context.Context, owner, repo string, number int, label string) (*github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.RemoveLabelErr != nil {
 		return nil, m.RemoveLabelErr
 	}
 	m.RemovedLabels = append(m.RemovedLabels, label)
+	m.RemovedLabelsByIssue[number] = append(m.RemovedLabelsByIssue[number], label)
+	return &github.Response{}, nil
+}
+
+func (m *IssuesClient) ListComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.Comments, &github.Response{NextPage: 0}, nil
+}
+
+func (m *IssuesClient) CreateComment(ctx context.Context, owner, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.CreatedComments = append(m.CreatedComments, comment)
+	return comment, &github.Response{}, nil
+}
+
+func (m *IssuesClient) EditComment(ctx context.Context, owner, repo string, commentID int64, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := commentID
+	comment.ID = &id
+	m.EditedComments = append(m.EditedComments, comment)
+	return comment, &github.Response{}, nil
+}
+
+func (m *IssuesClient) DeleteComment(ctx context.Context, owner, repo string, commentID int64) (*github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.DeletedComments = append(m.DeletedComments, commentID)
 	return &github.Response{}, nil
 }
 
 type PullRequestsClient struct {
+	mu sync.Mutex
+
 	FilesChanged []*github.CommitFile
+	// FilesChangedByPR maps a PR number to the files it changes, for PRs that
+	// don't change FilesChanged
+	FilesChangedByPR map[int][]*github.CommitFile
+	// ListFilesErrs are returned by the first ListFiles calls, in order
+	ListFilesErrs []error
+	PullRequests  []*github.PullRequest
 }
 
 func NewPullRequestsClient() *PullRequestsClient {
 	return &PullRequestsClient{
-		FilesChanged: []*github.CommitFile{},
+		FilesChanged:     []*github.CommitFile{},
+		FilesChangedByPR: make(map[int][]*github.CommitFile),
+		PullRequests:     []*github.PullRequest{},
 	}
 }
 
 func (m *PullRequestsClient) ListFiles(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.ListFilesErrs) > 0 {
+		err := m.ListFilesErrs[0]
+		m.ListFilesErrs = m.ListFilesErrs[1:]
+		return nil, nil, err
+	}
+	if files, ok := m.FilesChangedByPR[number]; ok {
+		return files, &github.Response{NextPage: 0}, nil
+	}
 	return m.FilesChanged, &github.Response{NextPage: 0}, nil
 }
+
+func (m *PullRequestsClient) List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prs := []*github.PullRequest{}
+	for _, pr := range m.PullRequests {
+		if opts.State == "all" || pr.GetState() == opts.State {
+			prs = append(prs, pr)
+		}
+	}
+	return prs, &github.Response{NextPage: 0}, nil
+}
+
+func (m *PullRequestsClient) Get(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, pr := range m.PullRequests {
+		if pr.GetNumber() == number {
+			return pr, &github.Response{}, nil
+		}
+	}
+	resp := &http.Response{StatusCode: http.StatusNotFound, Request: &http.Request{Method: http.MethodGet, URL: &url.URL{}}}
+	return nil, &github.Response{Response: resp}, &github.ErrorResponse{Response: resp, Message: "Not Found"}
+}
+
+type RepositoriesClient struct {
+	// Files maps a file path to its contents
+	Files map[string]string
+	// GetContentsRefs records the ref requested by each GetContents call
+	GetContentsRefs []string
+	GetContentsErr  error
+	// CreatedStatuses maps a ref to the statuses created for it
+	CreatedStatuses map[string][]*github.RepoStatus
+
+	// Permissions maps a user to their permission level on the repository
+	Permissions map[string]string
+}
+
+func NewRepositoriesClient() *RepositoriesClient {
+	return &RepositoriesClient{
+		Files:           make(map[string]string),
+		GetContentsRefs: []string{},
+		CreatedStatuses: make(map[string][]*github.RepoStatus),
+		Permissions:     make(map[string]string),
+	}
+}
+
+func (m *RepositoriesClient) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	m.GetContentsRefs = append(m.GetContentsRefs, opts.Ref)
+	if m.GetContentsErr != nil {
+		return nil, nil, &github.Response{Response: &http.Response{StatusCode: http.StatusInternalServerError}}, m.GetContentsErr
+	}
+
+	content, ok := m.Files[path]
+	if !ok {
+		resp := &http.Response{StatusCode: http.StatusNotFound, Request: &http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}}}
+		return nil, nil, &github.Response{Response: resp}, &github.ErrorResponse{Response: resp, Message: "Not Found"}
+	}
+	return &github.RepositoryContent{Path: &path, Content: &content}, nil, &github.Response{}, nil
+}
+
+func (m *RepositoriesClient) CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error) {
+	m.CreatedStatuses[ref] = append(m.CreatedStatuses[ref], status)
+	return status, &github.Response{}, nil
+}
+
+func (m *RepositoriesClient) GetPermissionLevel(ctx context.Context, owner, repo, user string) (*github.RepositoryPermissionLevel, *github.Response, error) {
+	permission, ok := m.Permissions[user]
+	if !ok {
+		permission = "none"
+	}
+	return &github.RepositoryPermissionLevel{Permission: &permission}, &github.Response{}, nil
+}
+
+type UsersClient struct {
+	// Login is the login of the authenticated user
+	Login  string
+	GetErr error
+}
+
+func NewUsersClient() *UsersClient {
+	return &UsersClient{Login: "labeler"}
+}
+
+func (m *UsersClient) Get(ctx context.Context, user string) (*github.User, *github.Response, error) {
+	if m.GetErr != nil {
+		return nil, &github.Response{Response: &http.Response{StatusCode: http.StatusForbidden}}, m.GetErr
+	}
+	if user == "" {
+		user = m.Login
+	}
+	return &github.User{Login: &user, Type: github.String("User")}, &github.Response{}, nil
+}
+
+type GitClient struct {
+	// Repositories is the client whose files are listed as the tree of every
+	// commit
+	Repositories *RepositoriesClient
+	// GetTreeSHAs records the commit requested by each GetTree call
+	GetTreeSHAs []string
+	GetTreeErr  error
+	// Truncated marks the trees listed as truncated
+	Truncated bool
+}
+
+func NewGitClient(repositories *RepositoriesClient) *GitClient {
+	return &GitClient{
+		Repositories: repositories,
+		GetTreeSHAs:  []string{},
+	}
+}
+
+func (m *GitClient) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error) {
+	m.GetTreeSHAs = append(m.GetTreeSHAs, sha)
+	if m.GetTreeErr != nil {
+		return nil, &github.Response{Response: &http.Response{StatusCode: http.StatusInternalServerError}}, m.GetTreeErr
+	}
+
+	tree := &github.Tree{SHA: &sha, Truncated: &m.Truncated}
+	paths := make([]string, 0, len(m.Repositories.Files))
+	for path := range m.Repositories.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		tree.Entries = append(tree.Entries, &github.TreeEntry{Path: github.String(path), Type: github.String("blob")})
+	}
+	return tree, &github.Response{}, nil
+}
+
+type ChecksClient struct {
+	CreatedCheckRuns  []github.CreateCheckRunOptions
+	CreateCheckRunErr error
+}
+
+func NewChecksClient() *ChecksClient {
+	return &ChecksClient{
+		CreatedCheckRuns: []github.CreateCheckRunOptions{},
+	}
+}
+
+func (m *ChecksClient) CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	if m.CreateCheckRunErr != nil {
+		return nil, nil, m.CreateCheckRunErr
+	}
+	m.CreatedCheckRuns = append(m.CreatedCheckRuns, opts)
+	return &github.CheckRun{Name: &opts.Name, HeadSHA: &opts.HeadSHA}, &github.Response{}, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/google/go-github/v50/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+// Provider is a code host whose pull requests, or merge requests, can be
+// sized and labeled. Its clients follow the GitHub API, so providers for other
+// code hosts translate to and from GitHub's types.
+type Provider interface {
+	Issues() IssuesClient
+	PullRequests() PullRequestsClient
+	Repositories() RepositoriesClient
+	Git() GitClient
+	Users() UsersClient
+	Checks() ChecksClient
+}
+
+// newProviderLabeler returns a labeler for the given repository of provider.
+func newProviderLabeler(provider Provider, action *githubactions.Action, config Config, owner, repo string) *GitHubPRSizeLabeler {
+	return newGitHubPRSizeLabeler(provider.Issues(), provider.PullRequests(), provider.Repositories(), provider.Git(), provider.Users(), provider.Checks(), action, config, owner, repo)
+}
+
+// githubProvider is the GitHub Provider.
+type githubProvider struct {
+	client *github.Client
+}
+
+func (p githubProvider) Issues() IssuesClient {
+	return p.client.Issues
+}
+
+func (p githubProvider) PullRequests() PullRequestsClient {
+	return p.client.PullRequests
+}
+
+func (p githubProvider) Repositories() RepositoriesClient {
+	return p.client.Repositories
+}
+
+func (p githubProvider) Git() GitClient {
+	return p.client.Git
+}
+
+func (p githubProvider) Users() UsersClient {
+	return p.client.Users
+}
+
+func (p githubProvider) Checks() ChecksClient {
+	return p.client.Checks
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/sethvargo/go-githubactions"
+)
+
+const (
+	// maxWebhookPayload is the largest payload GitHub sends in a webhook
+	// delivery.
+	maxWebhookPayload = 25 << 20
+	// shutdownTimeout is how long the server waits for open connections to
+	// close when it's stopped.
+	shutdownTimeout = 30 * time.Second
+)
+
+// webhookActions are the pull_request actions that PRs are labeled for, which
+// are the ones a pull_request workflow runs for by default.
+var webhookActions = map[string]bool{
+	"opened":      true,
+	"synchronize": true,
+	"reopened":    true,
+}
+
+// webhookServer labels PRs from pull_request webhook deliveries, so that PRs
+// across many repositories can be labeled without a workflow in each.
+// Deliveries are handled in the background after they're acknowledged, at
+// most one at a time per repository so that concurrent deliveries for the
+// same repository don't race to create labels or relabel the same PR.
+type webhookServer struct {
+	// secret is the webhook secret that deliveries are signed with.
+	secret []byte
+	config Config
+	logs   io.Writer
+	// provider returns the provider to handle a delivery of the given event
+	// with.
+	provider func(ctx context.Context, event *github.PullRequestEvent) (Provider, error)
+
+	mu    sync.Mutex
+	repos map[string]*repoLock
+	wg    sync.WaitGroup
+}
+
+// repoLock serializes the deliveries for a repository. It's removed from the
+// server once no delivery holds or waits for it, so that the server doesn't
+// keep a lock for every repository it has ever seen.
+type repoLock struct {
+	mu sync.Mutex
+	// refs is the number of deliveries holding or waiting for the lock,
+	// guarded by the server's mu.
+	refs int
+}
+
+func newWebhookServer(secret string, config Config, logs io.Writer, provider func(ctx context.Context, event *github.PullRequestEvent) (Provider, error)) *webhookServer {
+	return &webhookServer{
+		secret:   []byte(secret),
+		config:   config,
+		logs:     logs,
+		provider: provider,
+		repos:    make(map[string]*repoLock),
+	}
+}
+
+// ServeHTTP acknowledges a webhook delivery once its X-Hub-Signature-256
+// signature has been validated, and labels its PR in the background if it's a
+// pull_request event. Other events are ignored.
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayload))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") {
+		http.Error(w, "missing "+github.SHA256SignatureHeader+" signature", http.StatusUnauthorized)
+		return
+	}
+	if err := github.ValidateSignature(signature, payload, s.secret); err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	// Only pull_request events are parsed, since webhooks subscribed to more
+	// may send events this version of go-github doesn't know
+	if github.WebHookType(r) != "pull_request" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	prEvent, ok := event.(*github.PullRequestEvent)
+	if !ok || !webhookActions[prEvent.GetAction()] {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	delivery := github.DeliveryID(r)
+	ctx := context.WithoutCancel(r.Context())
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.handle(ctx, delivery, prEvent)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handle labels the PR of a delivery once no other delivery for the same
+// repository is being handled.
+func (s *webhookServer) handle(ctx context.Context, delivery string, event *github.PullRequestEvent) {
+	name := fmt.Sprintf("%s#%d", event.GetRepo().GetFullName(), event.GetNumber())
+	action := githubactions.New(
+		githubactions.WithWriter(&prefixWriter{w: s.logs, prefix: fmt.Sprintf("[%s %s] ", delivery, name)}),
+		// There is no job summary or outputs outside of GitHub Actions
+		githubactions.WithGetenv(func(string) string { return "" }),
+	)
+
+	unlock := s.lockRepo(event.GetRepo().GetFullName())
+	defer unlock()
+
+	action.Infof("Handling pull_request %s delivery", event.GetAction())
+	if err := s.label(ctx, action, event); err != nil {
+		action.Errorf("Failed to label %s: %v", name, err)
+	}
+}
+
+// label labels the PR of a pull_request event like the action does.
+func (s *webhookServer) label(ctx context.Context, action *githubactions.Action, event *github.PullRequestEvent) error {
+	provider, err := s.provider(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	labeler := newProviderLabeler(provider, action, s.config, event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName())
+	// There is no checkout to diff large PRs in, and the server's working
+	// directory isn't the repository's, so its .gitattributes don't apply
+	labeler.git = nil
+	labeler.config.LocalGitattributesFallback = false
+
+	if err := labeler.CreateSizeLabels(ctx); err != nil {
+		return err
+	}
+	return labeler.ForEvent(PullRequestEvent{event: *event}).AddSizeLabel(ctx)
+}
+
+// lockRepo waits until no other delivery for the given repository is being
+// handled, and returns a function that releases the repository.
+func (s *webhookServer) lockRepo(repo string) (unlock func()) {
+	s.mu.Lock()
+	lock, ok := s.repos[repo]
+	if !ok {
+		lock = &repoLock{}
+		s.repos[repo] = lock
+	}
+	lock.refs++
+	s.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.repos, repo)
+		}
+	}
+}
+
+// wait waits for the deliveries being handled in the background.
+func (s *webhookServer) wait() {
+	s.wg.Wait()
+}
+
+// prefixWriter prefixes each write, which is a single log line for an action,
+// so that the logs of concurrent deliveries can be told apart.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, p.prefix+string(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// tokenProvider returns a provider whose clients are created by newClient and
+// authenticate with the given token for every delivery.
+func tokenProvider(newClient func(*http.Client) *github.Client, token string) func(ctx context.Context, event *github.PullRequestEvent) (Provider, error) {
+	provider := githubProvider{client: newTokenClient(context.Background(), newClient, token)}
+	return func(context.Context, *github.PullRequestEvent) (Provider, error) {
+		return provider, nil
+	}
+}
+
+// appProvider returns a provider authenticated as the GitHub App installation
+// that each delivery was sent to.
+func appProvider(app *githubApp) func(ctx context.Context, event *github.PullRequestEvent) (Provider, error) {
+	return func(_ context.Context, event *github.PullRequestEvent) (Provider, error) {
+		installationID := event.GetInstallation().GetID()
+		if installationID == 0 {
+			return nil, errors.New("delivery wasn't sent to a GitHub App installation, is the webhook configured on the app?")
+		}
+		return githubProvider{client: app.installationClient(installationID)}, nil
+	}
+}
+
+// runServe implements the serve command, which runs a webhook server labeling
+// the PRs of pull_request deliveries until it's interrupted. The webhook
+// secret and credentials are read from environment variables so that they
+// don't end up in process listings: WEBHOOK_SECRET, and either
+// GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY to authenticate as the GitHub App
+// installation each delivery was sent to, or GITHUB_TOKEN.
+func runServe(ctx context.Context, args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", ":8080", "address to listen for webhook deliveries on")
+	configPath := flags.String("config", "pr-size-labeler.yml", "path to the pr-size-labeler config file used for every repository")
+	apiURL := flags.String("api-url", defaultAPIURL, "URL of the GitHub API, e.g. https://github.example.com/api/v3 for GitHub Enterprise Server")
+	uploadURL := flags.String("upload-url", "", "URL of the GitHub upload API, derived from --api-url by default")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		fmt.Fprintf(stderr, "missing required environment variable: WEBHOOK_SECRET\n")
+		return 1
+	}
+	newClient, err := newGitHubClientFunc(*apiURL, *uploadURL)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	provider, err := serveProvider(newClient)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	webhooks := newWebhookServer(secret, config, stderr, provider)
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           webhooks,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(stderr, "Listening for webhook deliveries on %s\n", *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	// Finish labeling the PRs of deliveries that were already acknowledged
+	webhooks.wait()
+	return 0
+}
+
+// serveProvider returns how the serve command authenticates deliveries, as
+// configured by environment variables, with clients created by newClient.
+func serveProvider(newClient func(*http.Client) *github.Client) (func(ctx context.Context, event *github.PullRequestEvent) (Provider, error), error) {
+	if appID := os.Getenv("GITHUB_APP_ID"); appID != "" {
+		id, err := strconv.ParseInt(appID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GITHUB_APP_ID: %q", appID)
+		}
+		privateKey := os.Getenv("GITHUB_APP_PRIVATE_KEY")
+		if privateKey == "" {
+			return nil, errors.New("missing required environment variable: GITHUB_APP_PRIVATE_KEY")
+		}
+		app, err := newGitHubApp(id, []byte(privateKey), newClient)
+		if err != nil {
+			return nil, err
+		}
+		return appProvider(app), nil
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return nil, errors.New("missing required environment variable: GITHUB_APP_ID or GITHUB_TOKEN")
+	}
+	return tokenProvider(newClient, token), nil
+}
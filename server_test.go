@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+// testProvider is a Provider with the given clients.
+type testProvider struct {
+	issues       IssuesClient
+	pullRequests PullRequestsClient
+	repositories RepositoriesClient
+	git          GitClient
+	users        UsersClient
+	checks       ChecksClient
+}
+
+func (p testProvider) Issues() IssuesClient             { return p.issues }
+func (p testProvider) PullRequests() PullRequestsClient { return p.pullRequests }
+func (p testProvider) Repositories() RepositoriesClient { return p.repositories }
+func (p testProvider) Git() GitClient                   { return p.git }
+func (p testProvider) Users() UsersClient               { return p.users }
+func (p testProvider) Checks() ChecksClient             { return p.checks }
+
+func testPullRequestPayload(action, repo string) string {
+	return `{"action": "` + action + `", "number": 1, "pull_request": {"number": 1, "head": {"sha": "` + testHeadSHA + `"}, "base": {"sha": "` + testBaseSHA + `", "repo": {"name": "` + repo + `", "owner": {"login": "owner"}}}}, "repository": {"name": "` + repo + `", "full_name": "owner/` + repo + `", "owner": {"login": "owner"}}}`
+}
+
+func signPayload(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newTestWebhookServer returns a webhook server with separate mock clients
+// for each repository, keyed by name.
+func newTestWebhookServer(repos ...string) (*webhookServer, map[string]*mocks.IssuesClient) {
+	issues := map[string]*mocks.IssuesClient{}
+	providers := map[string]Provider{}
+	for _, repo := range repos {
+		issues[repo] = mocks.NewIssuesClient()
+		prClient := mocks.NewPullRequestsClient()
+		prClient.FilesChanged = []*github.CommitFile{{Filename: ptr("main.go"), Additions: ptr(150), Deletions: ptr(0), Patch: ptr("")}}
+		reposClient := mocks.NewRepositoriesClient()
+		providers[repo] = testProvider{
+			issues:       issues[repo],
+			pullRequests: prClient,
+			repositories: reposClient,
+			git:          mocks.NewGitClient(reposClient),
+			users:        mocks.NewUsersClient(),
+			checks:       mocks.NewChecksClient(),
+		}
+	}
+
+	config := Config{Labels: []Label{{Name: "size/S", MinLines: 0}, {Name: "size/L", MinLines: 100}}}
+	server := newWebhookServer(testWebhookSecret, config, io.Discard, func(ctx context.Context, event *github.PullRequestEvent) (Provider, error) {
+		return providers[event.GetRepo().GetName()], nil
+	})
+	return server, issues
+}
+
+func postWebhook(t *testing.T, url, event, payload, signature string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(payload))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(github.EventTypeHeader, event)
+	req.Header.Set(github.DeliveryIDHeader, "delivery")
+	if signature != "" {
+		req.Header.Set(github.SHA256SignatureHeader, signature)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// notifyingIssuesClient signals added once it has added labels to a PR.
+type notifyingIssuesClient struct {
+	IssuesClient
+	added chan<- struct{}
+}
+
+func (c *notifyingIssuesClient) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error) {
+	defer func() { c.added <- struct{}{} }()
+	return c.IssuesClient.AddLabelsToIssue(ctx, owner, repo, number, labels)
+}
+
+func TestWebhookServer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		event          string
+		payload        string
+		signature      string
+		expectedStatus int
+		expectedAdded  []string
+	}{
+		{
+			name:           "labels opened PRs",
+			event:          "pull_request",
+			payload:        testPullRequestPayload("opened", "repo"),
+			signature:      signPayload(testWebhookSecret, testPullRequestPayload("opened", "repo")),
+			expectedStatus: http.StatusAccepted,
+			expectedAdded:  []string{"size/L"},
+		},
+		{
+			name:           "ignores other actions",
+			event:          "pull_request",
+			payload:        testPullRequestPayload("closed", "repo"),
+			signature:      signPayload(testWebhookSecret, testPullRequestPayload("closed", "repo")),
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "ignores other events",
+			event:          "push",
+			payload:        `{}`,
+			signature:      signPayload(testWebhookSecret, `{}`),
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "ignores events go-github doesn't know",
+			event:          "some_future_event",
+			payload:        `{}`,
+			signature:      signPayload(testWebhookSecret, `{}`),
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "rejects payloads signed with another secret",
+			event:          "pull_request",
+			payload:        testPullRequestPayload("opened", "repo"),
+			signature:      signPayload("other-secret", testPullRequestPayload("opened", "repo")),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "rejects unsigned payloads",
+			event:          "pull_request",
+			payload:        testPullRequestPayload("opened", "repo"),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "rejects invalid payloads",
+			event:          "pull_request",
+			payload:        `not json`,
+			signature:      signPayload(testWebhookSecret, `not json`),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, issues := newTestWebhookServer("repo")
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+
+			status := postWebhook(t, ts.URL, tc.event, tc.payload, tc.signature)
+			server.wait()
+
+			assert.Equal(t, tc.expectedStatus, status)
+			assert.ElementsMatch(t, tc.expectedAdded, issues["repo"].AddedLabels)
+		})
+	}
+}
+
+func TestWebhookServerRejectsOtherMethods(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestWebhookServer("repo")
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestWebhookServerSerializesDeliveriesPerRepo(t *testing.T) {
+	t.Parallel()
+
+	server, issues := newTestWebhookServer("busy", "idle")
+	idleLabeled := make(chan struct{}, 1)
+	providers := server.provider
+	server.provider = func(ctx context.Context, event *github.PullRequestEvent) (Provider, error) {
+		p, err := providers(ctx, event)
+		if event.GetRepo().GetName() == "idle" {
+			provider := p.(testProvider)
+			provider.issues = &notifyingIssuesClient{IssuesClient: provider.issues, added: idleLabeled}
+			return provider, err
+		}
+		return p, err
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// Hold the lock as if a delivery for busy were being handled
+	unlock := server.lockRepo("owner/busy")
+
+	busy := testPullRequestPayload("opened", "busy")
+	assert.Equal(t, http.StatusAccepted, postWebhook(t, ts.URL, "pull_request", busy, signPayload(testWebhookSecret, busy)))
+	idle := testPullRequestPayload("opened", "idle")
+	assert.Equal(t, http.StatusAccepted, postWebhook(t, ts.URL, "pull_request", idle, signPayload(testWebhookSecret, idle)))
+
+	// Deliveries for other repositories aren't held up
+	select {
+	case <-idleLabeled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery for idle wasn't handled while busy was locked")
+	}
+	assert.Equal(t, []string{"size/L"}, issues["idle"].AddedLabels)
+	assert.Empty(t, issues["busy"].CreatedLabels)
+
+	unlock()
+	server.wait()
+	assert.Equal(t, []string{"size/L"}, issues["busy"].AddedLabels)
+
+	// Locks are removed once no delivery needs them
+	assert.Empty(t, server.repos)
+}
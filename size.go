@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// prSize is the calculated size of a PR.
+type prSize struct {
+	// LinesChanged is the number of lines added and deleted across all files.
+	LinesChanged int
+	// LinesCounted is the number of lines changed after exclusions and
+	// weights are applied. This is what the size label is based on.
+	LinesCounted int
+	// FilesChanged is the number of files changed.
+	FilesChanged int
+	// FilesIgnored is the number of files excluded from LinesCounted.
+	FilesIgnored int
+	// Files is the size of each file changed.
+	Files []fileSize
+}
+
+// fileSize is the size of a single file changed in a PR.
+type fileSize struct {
+	Filename  string
+	Additions int
+	Deletions int
+	// LinesCounted is the weighted number of lines the file contributes to
+	// the PR's size.
+	LinesCounted float64
+	// IgnoredReason is why the file was excluded from the PR's size, or an
+	// empty string if it wasn't.
+	IgnoredReason string
+}
+
+// gitattributesLoader returns the contents of the .gitattributes file in dir
+// at the base of a change. found is false if there is no such file.
+type gitattributesLoader func(ctx context.Context, dir string) (content []byte, found bool, err error)
+
+// sizer calculates the size of a set of changed files according to the
+// config. It knows nothing about where the changes come from, so the same
+// rules apply whether they're fetched from GitHub or read from a local
+// checkout.
+type sizer struct {
+	action                *githubactions.Action
+	config                Config
+	loadGitattributesFile gitattributesLoader
+}
+
+// Size calculates the size of the given changes. Files matching any of the
+// exclude-paths globs, with any of the ignored attributes in the
+// .gitattributes files at the base of the change, or that are binary are
+// ignored. The rest are counted according to the weights and count-mode.
+func (s *sizer) Size(ctx context.Context, filesChanged []FileChange) (prSize, error) {
+	var attrs *attributes
+	ignoredAttributes := s.config.ignoredAttributes()
+
+	if len(ignoredAttributes) == 0 {
+		s.action.Infof("No .gitattributes exclusions are enabled, skipping .gitattributes checks")
+	} else {
+		var err error
+		attrs, err = s.loadAttributes(ctx, filesChanged)
+		if err != nil {
+			return prSize{}, err
+		}
+
+		if attrs == nil {
+			s.action.Infof("No .gitattributes files found, skipping .gitattributes checks")
+		} else {
+			s.action.Infof("Ignoring files with attributes %s based on .gitattributes files", strings.Join(ignoredAttributes, ", "))
+		}
+	}
+
+	size := prSize{FilesChanged: len(filesChanged)}
+	var weightedLines float64
+	// Weighted lines contributed by each weights rule, indexed like s.config.Weights
+	contributions := make([]float64, len(s.config.Weights))
+	for _, change := range filesChanged {
+		file := fileSize{
+			Filename:  change.Filename,
+			Additions: change.Additions,
+			Deletions: change.Deletions,
+		}
+		size.LinesChanged += file.Additions + file.Deletions
+
+		if pattern, ok := s.config.matchExcludePath(file.Filename); ok {
+			s.action.Debugf("Skipping file %s matching exclude-paths pattern %s", file.Filename, pattern)
+			file.IgnoredReason = fmt.Sprintf("excluded by `%s`", pattern)
+		} else if attr, ok := attrs.AnySet(file.Filename, ignoredAttributes); ok {
+			s.action.Debugf("Skipping file %s with attribute %s", file.Filename, attr)
+			file.IgnoredReason = attr
+		} else if change.Binary {
+			s.action.Debugf("Skipping binary file %s", file.Filename)
+			file.IgnoredReason = "binary"
+		}
+
+		if file.IgnoredReason != "" {
+			size.FilesIgnored++
+			size.Files = append(size.Files, file)
+			continue
+		}
+
+		i, matched := s.config.matchWeight(file.Filename)
+		var rule *Weight
+		if matched {
+			rule = &s.config.Weights[i]
+		}
+
+		file.LinesCounted = s.config.countLines(file.Additions, file.Deletions, rule)
+		if matched {
+			contributions[i] += file.LinesCounted
+		}
+		weightedLines += file.LinesCounted
+		size.Files = append(size.Files, file)
+	}
+
+	for i, rule := range s.config.Weights {
+		s.action.Infof("Weight rule %s (x%g) contributed %g lines", rule.Path, rule.Weight, contributions[i])
+	}
+
+	size.LinesCounted = int(math.Round(weightedLines))
+	return size, nil
+}
+
+// loadAttributes loads the .gitattributes files at the base of the change from
+// every directory containing a changed file, giving files in subdirectories
+// precedence over those in their parent directories like git does. It returns
+// nil if there are no .gitattributes files.
+func (s *sizer) loadAttributes(ctx context.Context, filesChanged []FileChange) (*attributes, error) {
+	filenames := make([]string, len(filesChanged))
+	for i, change := range filesChanged {
+		filenames[i] = change.Filename
+	}
+
+	var attrs *attributes
+	for _, dir := range attributesDirs(filenames) {
+		content, found, err := s.loadGitattributesFile(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+
+		dirAttrs, err := parseAttributes(dir, content)
+		if err != nil {
+			return nil, err
+		}
+		if attrs == nil {
+			attrs = &attributes{}
+		}
+		attrs.Merge(dirAttrs)
+		s.action.Infof("Loaded %s", path.Join(dir, ".gitattributes"))
+	}
+	return attrs, nil
+}
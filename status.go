@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v50/github"
+)
+
+const defaultCommitStatusContext = "pr-size"
+
+// CreateCommitStatus sets a commit status on the PR's head commit reporting
+// its size, e.g. "412 lines (size/L)". The status fails when the check run
+// conclusion would be failure and succeeds otherwise, since statuses have no
+// neutral state.
+func (l *GitHubPRSizeLabeler) CreateCommitStatus(ctx context.Context, size prSize, label, limitViolation string) error {
+	statusContext := l.config.CommitStatus.Context
+	if statusContext == "" {
+		statusContext = defaultCommitStatusContext
+	}
+
+	state := "success"
+	if l.checkConclusion(label, limitViolation) == "failure" {
+		state = "failure"
+	}
+
+	description := fmt.Sprintf("%d lines", size.LinesCounted)
+	if label != "" {
+		description = fmt.Sprintf("%d lines (%s)", size.LinesCounted, label)
+	}
+
+	l.action.Infof("Setting commit status %s to %s: %s", statusContext, state, description)
+	_, _, err := l.repositories.CreateStatus(ctx, l.event.RepoOwner(), l.event.RepoName(), l.event.HeadSHA(), &github.RepoStatus{
+		State:       &state,
+		Context:     &statusContext,
+		Description: &description,
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/ngrok/pr-size-labeler/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddSizeLabelCreatesCommitStatus(t *testing.T) {
+	t.Parallel()
+
+	labels := []Label{
+		{Name: "size/S", MinLines: 0},
+		{Name: "size/L", MinLines: 100, CheckConclusion: "neutral"},
+		{Name: "size/XXL", MinLines: 1000, CheckConclusion: "failure"},
+	}
+
+	tests := []struct {
+		name                string
+		lines               int
+		commitStatus        CommitStatusConfig
+		limit               LimitConfig
+		expectedContext     string
+		expectedState       string
+		expectedDescription string
+	}{
+		{
+			name:                "success with default context",
+			lines:               412,
+			commitStatus:        CommitStatusConfig{Enabled: true},
+			expectedContext:     "pr-size",
+			expectedState:       "success",
+			expectedDescription: "412 lines (size/L)",
+		},
+		{
+			name:                "custom context",
+			lines:               5,
+			commitStatus:        CommitStatusConfig{Enabled: true, Context: "size"},
+			expectedContext:     "size",
+			expectedState:       "success",
+			expectedDescription: "5 lines (size/S)",
+		},
+		{
+			name:                "failure from label policy",
+			lines:               1500,
+			commitStatus:        CommitStatusConfig{Enabled: true},
+			expectedContext:     "pr-size",
+			expectedState:       "failure",
+			expectedDescription: "1500 lines (size/XXL)",
+		},
+		{
+			name:                "failure when exceeding the limit",
+			lines:               500,
+			commitStatus:        CommitStatusConfig{Enabled: true},
+			limit:               LimitConfig{MaxLines: 400},
+			expectedContext:     "pr-size",
+			expectedState:       "failure",
+			expectedDescription: "500 lines (size/L)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockPR := mocks.NewPullRequestsClient()
+			mockPR.FilesChanged = []*github.CommitFile{
+				{Filename: ptr("file1.go"), Additions: ptr(tt.lines), Deletions: ptr(0)},
+			}
+			mockRepos := mocks.NewRepositoriesClient()
+
+			labeler := newTestLabeler(
+				newTestGitHubPullRequestEvent(1, "repo", "owner", []string{}),
+				Config{Labels: labels, CommitStatus: tt.commitStatus, Limit: tt.limit},
+				mocks.NewIssuesClient(),
+				mockPR,
+				mockRepos,
+			)
+
+			// The limit's error is covered elsewhere
+			_ = labeler.AddSizeLabel(t.Context())
+
+			statuses := mockRepos.CreatedStatuses[testHeadSHA]
+			assert.Len(t, statuses, 1)
+			assert.Equal(t, tt.expectedContext, *statuses[0].Context)
+			assert.Equal(t, tt.expectedState, *statuses[0].State)
+			assert.Equal(t, tt.expectedDescription, *statuses[0].Description)
+		})
+	}
+}
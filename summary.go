@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// commentMarker is a hidden marker included in the PR comment so that it can
+// be found and updated on later runs.
+const commentMarker = "<!-- pr-size-labeler -->"
+
+// renderSummary renders a Markdown summary of the PR's size, the label it
+// was given and the configured thresholds, and a breakdown of each file
+// changed, largest first. If the PR breaks the size limit, limitViolation
+// describes how.
+func renderSummary(prNumber int, size prSize, labels []Label, label, limitViolation string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## PR Size\n\n")
+	writeHeadline(&b, prNumber, size, labels, label)
+	if limitViolation != "" {
+		fmt.Fprintf(&b, "> [!CAUTION]\n> %s.\n\n", limitViolation)
+	}
+	writeThresholds(&b, labels, label)
+	fmt.Fprintf(&b, "\n")
+	writeFiles(&b, size)
+
+	return b.String()
+}
+
+// renderComment renders the body of the PR comment explaining its size. The
+// breakdown is collapsed so that the comment doesn't take over the PR.
+func renderComment(prNumber int, size prSize, labels []Label, label string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n", commentMarker)
+	fmt.Fprintf(&b, "## PR Size\n\n")
+	writeHeadline(&b, prNumber, size, labels, label)
+	fmt.Fprintf(&b, "<details>\n<summary>Size breakdown</summary>\n\n")
+	writeThresholds(&b, labels, label)
+	fmt.Fprintf(&b, "\n")
+	writeFiles(&b, size)
+	fmt.Fprintf(&b, "\n</details>\n")
+
+	return b.String()
+}
+
+// writeHeadline writes the PR's size and label, followed by the label's
+// guidance if it has any.
+func writeHeadline(w io.Writer, prNumber int, size prSize, labels []Label, label string) {
+	fmt.Fprintf(w, "PR #%d has **%d** lines changed", prNumber, size.LinesCounted)
+	if label != "" {
+		fmt.Fprintf(w, " and is labeled `%s`", label)
+	}
+	fmt.Fprintf(w, ".\n\n")
+	fmt.Fprintf(w, "%d lines were changed across %d files, of which %d were ignored.\n\n",
+		size.LinesChanged, size.FilesChanged, size.FilesIgnored)
+
+	for _, l := range labels {
+		if l.Name == label && l.Guidance != "" {
+			fmt.Fprintf(w, "%s\n\n", l.Guidance)
+		}
+	}
+}
+
+// writeThresholds writes a table of the configured labels from smallest to
+// largest, marking the PR's label.
+func writeThresholds(w io.Writer, labels []Label, label string) {
+	sortedLabels := make([]Label, len(labels))
+	copy(sortedLabels, labels)
+	sort.Slice(sortedLabels, func(i, j int) bool {
+		return sortedLabels[i].MinLines < sortedLabels[j].MinLines
+	})
+
+	fmt.Fprintf(w, "### Thresholds\n\n")
+	fmt.Fprintf(w, "| Label | Min lines | |\n")
+	fmt.Fprintf(w, "| --- | ---: | --- |\n")
+	for _, l := range sortedLabels {
+		var current string
+		if l.Name == label {
+			current = "⬅️"
+		}
+		fmt.Fprintf(w, "| `%s` | %d | %s |\n", l.Name, l.MinLines, current)
+	}
+}
+
+// writeFiles writes a table of the files changed, largest first, along with
+// why any of them were ignored.
+func writeFiles(w io.Writer, size prSize) {
+	fmt.Fprintf(w, "### Files\n\n")
+	fmt.Fprintf(w, "| File | Additions | Deletions | Counted | Ignored |\n")
+	fmt.Fprintf(w, "| --- | ---: | ---: | ---: | --- |\n")
+	for _, f := range sortedFiles(size) {
+		fmt.Fprintf(w, "| %s | %d | %d | %g | %s |\n",
+			tableCode(f.Filename), f.Additions, f.Deletions, f.LinesCounted, f.IgnoredReason)
+	}
+}
+
+// tableCode formats s as inline code in a table cell. The code span is
+// delimited by more backticks than s contains in a row, and pipes and line
+// breaks are escaped, so that no filename can break out of its cell.
+func tableCode(s string) string {
+	s = strings.NewReplacer("|", `\|`, "\r", " ", "\n", " ").Replace(s)
+
+	longest, run := 0, 0
+	for _, r := range s {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// sortedFiles returns the files changed, largest first.
+func sortedFiles(size prSize) []fileSize {
+	files := make([]fileSize, len(size.Files))
+	copy(files, size.Files)
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].LinesCounted != files[j].LinesCounted {
+			return files[i].LinesCounted > files[j].LinesCounted
+		}
+		ci, cj := files[i].Additions+files[i].Deletions, files[j].Additions+files[j].Deletions
+		if ci != cj {
+			return ci > cj
+		}
+		return files[i].Filename < files[j].Filename
+	})
+	return files
+}
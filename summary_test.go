@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderSummary(t *testing.T) {
+	t.Parallel()
+
+	size := prSize{
+		LinesChanged: 365,
+		LinesCounted: 115,
+		FilesChanged: 5,
+		FilesIgnored: 3,
+		Files: []fileSize{
+			{Filename: "small.go", Additions: 4, Deletions: 1, LinesCounted: 5},
+			{Filename: "vendor/foo/bar.go", Additions: 200, Deletions: 0, IgnoredReason: "excluded by `vendor/**`"},
+			{Filename: "logo.png", IgnoredReason: "binary"},
+			{Filename: "big.go", Additions: 80, Deletions: 30, LinesCounted: 110},
+			{Filename: "client.gen.go", Additions: 50, Deletions: 0, IgnoredReason: "linguist-generated"},
+		},
+	}
+	labels := []Label{
+		{Name: "size/M", MinLines: 100},
+		{Name: "size/XS", MinLines: 0},
+		{Name: "size/S", MinLines: 10},
+	}
+
+	expected := "## PR Size\n\n" +
+		"PR #42 has **115** lines changed and is labeled `size/M`.\n\n" +
+		"365 lines were changed across 5 files, of which 3 were ignored.\n\n" +
+		"### Thresholds\n\n" +
+		"| Label | Min lines | |\n" +
+		"| --- | ---: | --- |\n" +
+		"| `size/XS` | 0 |  |\n" +
+		"| `size/S` | 10 |  |\n" +
+		"| `size/M` | 100 | ⬅️ |\n" +
+		"\n### Files\n\n" +
+		"| File | Additions | Deletions | Counted | Ignored |\n" +
+		"| --- | ---: | ---: | ---: | --- |\n" +
+		"| `big.go` | 80 | 30 | 110 |  |\n" +
+		"| `small.go` | 4 | 1 | 5 |  |\n" +
+		"| `vendor/foo/bar.go` | 200 | 0 | 0 | excluded by `vendor/**` |\n" +
+		"| `client.gen.go` | 50 | 0 | 0 | linguist-generated |\n" +
+		"| `logo.png` | 0 | 0 | 0 | binary |\n"
+
+	assert.Equal(t, expected, renderSummary(42, size, labels, "size/M", ""))
+	// The labels passed in shouldn't be reordered
+	assert.Equal(t, "size/M", labels[0].Name)
+}
+
+func TestRenderSummaryLimitViolation(t *testing.T) {
+	t.Parallel()
+
+	summary := renderSummary(1, prSize{LinesCounted: 2000}, []Label{}, "", "PR #1 has 2000 lines changed, exceeding the limit of 1000 set by limit.max-lines")
+	assert.Contains(t, summary, "> [!CAUTION]\n> PR #1 has 2000 lines changed, exceeding the limit of 1000 set by limit.max-lines.\n")
+}
+
+func TestRenderComment(t *testing.T) {
+	t.Parallel()
+
+	size := prSize{
+		LinesChanged: 5,
+		LinesCounted: 5,
+		FilesChanged: 1,
+		Files: []fileSize{
+			{Filename: "main.go", Additions: 4, Deletions: 1, LinesCounted: 5},
+		},
+	}
+	labels := []Label{
+		{Name: "size/XS", MinLines: 0, Guidance: "Thanks for keeping it small!"},
+		{Name: "size/S", MinLines: 10},
+	}
+
+	expected := commentMarker + "\n" +
+		"## PR Size\n\n" +
+		"PR #7 has **5** lines changed and is labeled `size/XS`.\n\n" +
+		"5 lines were changed across 1 files, of which 0 were ignored.\n\n" +
+		"Thanks for keeping it small!\n\n" +
+		"<details>\n<summary>Size breakdown</summary>\n\n" +
+		"### Thresholds\n\n" +
+		"| Label | Min lines | |\n" +
+		"| --- | ---: | --- |\n" +
+		"| `size/XS` | 0 | ⬅️ |\n" +
+		"| `size/S` | 10 |  |\n" +
+		"\n### Files\n\n" +
+		"| File | Additions | Deletions | Counted | Ignored |\n" +
+		"| --- | ---: | ---: | ---: | --- |\n" +
+		"| `main.go` | 4 | 1 | 5 |  |\n" +
+		"\n</details>\n"
+
+	assert.Equal(t, expected, renderComment(7, size, labels, "size/XS"))
+}
+
+func TestTableCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{filename: "main.go", expected: "`main.go`"},
+		{filename: "a|b.go", expected: "`a\\|b.go`"},
+		{filename: "a`b``c.go", expected: "```a`b``c.go```"},
+		{filename: "`start.go", expected: "`` `start.go ``"},
+		{filename: "line\nbreak.go", expected: "`line break.go`"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, tableCode(tc.filename))
+		})
+	}
+}